@@ -9,4 +9,7 @@ type CompControllers interface {
 	Login(ctx *gin.Context)
 	Refresh(ctx *gin.Context)
 	Logout(ctx *gin.Context)
+	SessionLogin(ctx *gin.Context)
+	Session(ctx *gin.Context)
+	SessionLogout(ctx *gin.Context)
 }
@@ -5,17 +5,22 @@ import (
 	"xanny-go/api/users/dto"
 	"xanny-go/api/users/services"
 	"xanny-go/pkg/exceptions"
+	"xanny-go/pkg/helpers"
+	"xanny-go/pkg/logger"
+	"xanny-go/pkg/session"
 
 	"github.com/gin-gonic/gin"
 )
 
 type CompControllersImpl struct {
 	services services.CompServices
+	sessions *session.Manager
 }
 
-func NewCompController(compServices services.CompServices) CompControllers {
+func NewCompController(compServices services.CompServices, sessions *session.Manager) CompControllers {
 	return &CompControllersImpl{
 		services: compServices,
+		sessions: sessions,
 	}
 }
 
@@ -33,9 +38,8 @@ func NewCompController(compServices services.CompServices) CompControllers {
 func (h *CompControllersImpl) Create(ctx *gin.Context) {
 	var data dto.Users
 
-	jsonErr := ctx.ShouldBindJSON(&data)
-	if jsonErr != nil {
-		ctx.JSON(http.StatusBadRequest, exceptions.NewException(http.StatusBadRequest, exceptions.ErrBadRequest))
+	if bindErr := helpers.BindJSON(ctx, &data); bindErr != nil {
+		ctx.JSON(bindErr.Status, bindErr)
 		return
 	}
 
@@ -102,8 +106,8 @@ func (h *CompControllersImpl) VerificationEmail(ctx *gin.Context) {
 // @Router /user/login [post]
 func (h *CompControllersImpl) Login(ctx *gin.Context) {
 	var req dto.LoginRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, exceptions.NewException(http.StatusBadRequest, "Invalid request body"))
+	if bindErr := helpers.BindJSON(ctx, &req); bindErr != nil {
+		ctx.JSON(bindErr.Status, bindErr)
 		return
 	}
 	accessToken, refreshToken, err := h.services.Login(ctx, req.Email, req.Password)
@@ -130,8 +134,8 @@ func (h *CompControllersImpl) Login(ctx *gin.Context) {
 // @Router /user/refresh [post]
 func (h *CompControllersImpl) Refresh(ctx *gin.Context) {
 	var req dto.RefreshTokenRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, exceptions.NewException(http.StatusBadRequest, "Invalid request body"))
+	if bindErr := helpers.BindJSON(ctx, &req); bindErr != nil {
+		ctx.JSON(bindErr.Status, bindErr)
 		return
 	}
 	accessToken, err := h.services.RefreshToken(ctx, req.RefreshToken)
@@ -156,8 +160,8 @@ func (h *CompControllersImpl) Refresh(ctx *gin.Context) {
 // @Router /user/logout [post]
 func (h *CompControllersImpl) Logout(ctx *gin.Context) {
 	var req dto.LogoutRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, exceptions.NewException(http.StatusBadRequest, "Invalid request body"))
+	if bindErr := helpers.BindJSON(ctx, &req); bindErr != nil {
+		ctx.JSON(bindErr.Status, bindErr)
 		return
 	}
 	err := h.services.Logout(ctx, req.AccessToken, req.RefreshToken)
@@ -167,3 +171,75 @@ func (h *CompControllersImpl) Logout(ctx *gin.Context) {
 	}
 	ctx.JSON(http.StatusOK, gin.H{"message": "logout success"})
 }
+
+// SessionLogin godoc
+// @Summary Cookie session login
+// @Description Authenticate user and start a server-side session carried in an HttpOnly cookie
+// @Tags session
+// @Accept json
+// @Produce json
+// @Param login body dto.LoginRequest true "Login credentials"
+// @Success 200 {object} dto.SessionResponse
+// @Failure 400 {object} exceptions.Exception
+// @Failure 401 {object} exceptions.Exception
+// @Router /session/login [post]
+func (h *CompControllersImpl) SessionLogin(ctx *gin.Context) {
+	var req dto.LoginRequest
+	if bindErr := helpers.BindJSON(ctx, &req); bindErr != nil {
+		ctx.JSON(bindErr.Status, bindErr)
+		return
+	}
+	user, err := h.services.Authenticate(ctx, req.Email, req.Password)
+	if err != nil {
+		ctx.JSON(err.Status, err)
+		return
+	}
+	current, startErr := h.sessions.Start(ctx, user.UUID, map[string]string{
+		"email": user.Email,
+		"name":  user.Name,
+	})
+	if startErr != nil {
+		logger.Error("Failed to start session: %v", startErr)
+		ctx.JSON(http.StatusInternalServerError, exceptions.NewException(http.StatusInternalServerError, exceptions.ErrInternalServer))
+		return
+	}
+	ctx.JSON(http.StatusOK, dto.SessionResponse{
+		User:      *user,
+		CSRFToken: current.CSRFToken,
+	})
+}
+
+// Session godoc
+// @Summary Current session
+// @Description Return the signed-in user and the CSRF token of the cookie session
+// @Tags session
+// @Produce json
+// @Success 200 {object} dto.SessionResponse
+// @Failure 401 {object} exceptions.Exception
+// @Router /session [get]
+func (h *CompControllersImpl) Session(ctx *gin.Context) {
+	current, _ := session.FromContext(ctx)
+	ctx.JSON(http.StatusOK, dto.SessionResponse{
+		User:      ctx.MustGet("user").(dto.UserOutput),
+		CSRFToken: current.CSRFToken,
+	})
+}
+
+// SessionLogout godoc
+// @Summary Cookie session logout
+// @Description Destroy the cookie session. Requires the X-CSRF-Token header
+// @Tags session
+// @Produce json
+// @Param X-CSRF-Token header string true "CSRF token from login or GET /session"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} exceptions.Exception
+// @Failure 403 {object} exceptions.Exception
+// @Router /session/logout [post]
+func (h *CompControllersImpl) SessionLogout(ctx *gin.Context) {
+	if err := h.sessions.End(ctx); err != nil {
+		logger.Error("Failed to end session: %v", err)
+		ctx.JSON(http.StatusInternalServerError, exceptions.NewException(http.StatusInternalServerError, exceptions.ErrInternalServer))
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "logout success"})
+}
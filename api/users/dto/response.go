@@ -14,3 +14,10 @@ type UserOutput struct {
 	IsEmailVerified bool   `json:"is_email_verified"`
 	Name            string `json:"name" example:"John Doe"`
 }
+
+// SessionResponse represents the signed-in user of a cookie session. Send
+// CSRFToken back in the X-CSRF-Token header on unsafe requests
+type SessionResponse struct {
+	User      UserOutput `json:"user"`
+	CSRFToken string     `json:"csrf_token" example:"3q2-7wEXAMPLEtokenValue"`
+}
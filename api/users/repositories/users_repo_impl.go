@@ -1,13 +1,23 @@
 package repositories
 
 import (
+	"time"
 	"xanny-go/models"
+	"xanny-go/pkg/cache"
 	"xanny-go/pkg/exceptions"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// userQueryCacheTTL bounds how long a user lookup is served from the query
+// cache; writes through gorm invalidate it sooner
+const userQueryCacheTTL = time.Minute
+
+// userCredentialColumns are never read through the query cache, so password
+// hashes stay out of Redis
+var userCredentialColumns = []string{"hashed_password"}
+
 type CompRepositoriesImpl struct {
 }
 
@@ -16,7 +26,7 @@ func NewComponentRepository() CompRepositories {
 }
 
 func (r *CompRepositoriesImpl) Create(ctx *gin.Context, tx *gorm.DB, data models.Users) *exceptions.Exception {
-	result := tx.Create(&data)
+	result := tx.WithContext(ctx).Create(&data)
 	if result.Error != nil {
 		return exceptions.ParseGormError(tx, result.Error)
 	}
@@ -26,16 +36,18 @@ func (r *CompRepositoriesImpl) Create(ctx *gin.Context, tx *gorm.DB, data models
 
 func (r *CompRepositoriesImpl) FindByUUID(ctx *gin.Context, tx *gorm.DB, uuid string) (*models.Users, *exceptions.Exception) {
 	var user models.Users
-	err := tx.Where("uuid = ?", uuid).First(&user).Error
+	err := cache.WithQueryCache(tx.WithContext(ctx), userQueryCacheTTL).Omit(userCredentialColumns...).Where("uuid = ?", uuid).First(&user).Error
 	if err != nil {
 		return nil, exceptions.ParseGormError(tx, err)
 	}
 	return &user, nil
 }
 
+// FindByEmail is the login lookup and needs the password hash, so it always
+// goes to the database
 func (r *CompRepositoriesImpl) FindByEmail(ctx *gin.Context, tx *gorm.DB, email string) (*models.Users, *exceptions.Exception) {
 	var user models.Users
-	err := tx.Where("email = ?", email).First(&user).Error
+	err := tx.WithContext(ctx).Where("email = ?", email).First(&user).Error
 	if err != nil {
 		return nil, exceptions.ParseGormError(tx, err)
 	}
@@ -43,7 +55,7 @@ func (r *CompRepositoriesImpl) FindByEmail(ctx *gin.Context, tx *gorm.DB, email
 }
 
 func (r *CompRepositoriesImpl) Update(ctx *gin.Context, tx *gorm.DB, data models.Users) *exceptions.Exception {
-	result := tx.Where("uuid = ?", data.UUID).Updates(&data)
+	result := tx.WithContext(ctx).Where("uuid = ?", data.UUID).Updates(&data)
 	if result.Error != nil {
 		return exceptions.ParseGormError(tx, result.Error)
 	}
@@ -52,7 +64,7 @@ func (r *CompRepositoriesImpl) Update(ctx *gin.Context, tx *gorm.DB, data models
 }
 
 func (r *CompRepositoriesImpl) CreateRefreshToken(ctx *gin.Context, tx *gorm.DB, token models.RefreshToken) *exceptions.Exception {
-	if err := tx.Create(&token).Error; err != nil {
+	if err := tx.WithContext(ctx).Create(&token).Error; err != nil {
 		return exceptions.ParseGormError(tx, err)
 	}
 	return nil
@@ -60,7 +72,7 @@ func (r *CompRepositoriesImpl) CreateRefreshToken(ctx *gin.Context, tx *gorm.DB,
 
 func (r *CompRepositoriesImpl) FindRefreshToken(ctx *gin.Context, tx *gorm.DB, token string) (*models.RefreshToken, *exceptions.Exception) {
 	var refreshToken models.RefreshToken
-	err := tx.Where("token = ?", token).First(&refreshToken).Error
+	err := tx.WithContext(ctx).Where("token = ?", token).First(&refreshToken).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -71,14 +83,14 @@ func (r *CompRepositoriesImpl) FindRefreshToken(ctx *gin.Context, tx *gorm.DB, t
 }
 
 func (r *CompRepositoriesImpl) DeleteRefreshToken(ctx *gin.Context, tx *gorm.DB, token string) *exceptions.Exception {
-	if err := tx.Where("token = ?", token).Delete(&models.RefreshToken{}).Error; err != nil {
+	if err := tx.WithContext(ctx).Where("token = ?", token).Delete(&models.RefreshToken{}).Error; err != nil {
 		return exceptions.ParseGormError(tx, err)
 	}
 	return nil
 }
 
 func (r *CompRepositoriesImpl) CreateBlacklistedToken(ctx *gin.Context, tx *gorm.DB, token models.BlacklistedToken) *exceptions.Exception {
-	if err := tx.Create(&token).Error; err != nil {
+	if err := tx.WithContext(ctx).Create(&token).Error; err != nil {
 		return exceptions.ParseGormError(tx, err)
 	}
 	return nil
@@ -86,7 +98,7 @@ func (r *CompRepositoriesImpl) CreateBlacklistedToken(ctx *gin.Context, tx *gorm
 
 func (r *CompRepositoriesImpl) FindBlacklistedToken(ctx *gin.Context, tx *gorm.DB, token string) (bool, *exceptions.Exception) {
 	var blacklisted models.BlacklistedToken
-	err := tx.Where("token = ?", token).First(&blacklisted).Error
+	err := tx.WithContext(ctx).Where("token = ?", token).First(&blacklisted).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return false, nil
@@ -97,7 +109,7 @@ func (r *CompRepositoriesImpl) FindBlacklistedToken(ctx *gin.Context, tx *gorm.D
 }
 
 func (r *CompRepositoriesImpl) CreateVerificationToken(ctx *gin.Context, tx *gorm.DB, token models.VerificationToken) *exceptions.Exception {
-	if err := tx.Create(&token).Error; err != nil {
+	if err := tx.WithContext(ctx).Create(&token).Error; err != nil {
 		return exceptions.ParseGormError(tx, err)
 	}
 	return nil
@@ -105,7 +117,7 @@ func (r *CompRepositoriesImpl) CreateVerificationToken(ctx *gin.Context, tx *gor
 
 func (r *CompRepositoriesImpl) FindVerificationToken(ctx *gin.Context, tx *gorm.DB, token string) (*models.VerificationToken, *exceptions.Exception) {
 	var verification models.VerificationToken
-	err := tx.Where("token = ?", token).First(&verification).Error
+	err := tx.WithContext(ctx).Where("token = ?", token).First(&verification).Error
 	if err != nil {
 		return nil, exceptions.ParseGormError(tx, err)
 	}
@@ -114,7 +126,7 @@ func (r *CompRepositoriesImpl) FindVerificationToken(ctx *gin.Context, tx *gorm.
 
 func (r *CompRepositoriesImpl) FindVerificationTokenByUserUUID(ctx *gin.Context, tx *gorm.DB, userUUID string) (*models.VerificationToken, *exceptions.Exception) {
 	var verification models.VerificationToken
-	err := tx.Where("user_uuid = ?", userUUID).First(&verification).Error
+	err := tx.WithContext(ctx).Where("user_uuid = ?", userUUID).First(&verification).Error
 	if err != nil {
 		return nil, exceptions.ParseGormError(tx, err)
 	}
@@ -122,7 +134,7 @@ func (r *CompRepositoriesImpl) FindVerificationTokenByUserUUID(ctx *gin.Context,
 }
 
 func (r *CompRepositoriesImpl) DeleteVerificationToken(ctx *gin.Context, tx *gorm.DB, token string) *exceptions.Exception {
-	if err := tx.Where("token = ?", token).Delete(&models.VerificationToken{}).Error; err != nil {
+	if err := tx.WithContext(ctx).Where("token = ?", token).Delete(&models.VerificationToken{}).Error; err != nil {
 		return exceptions.ParseGormError(tx, err)
 	}
 	return nil
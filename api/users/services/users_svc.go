@@ -10,6 +10,7 @@ import (
 type CompServices interface {
 	Create(ctx *gin.Context, data dto.Users) *exceptions.Exception
 	Login(ctx *gin.Context, email, password string) (accessToken, refreshToken string, err *exceptions.Exception)
+	Authenticate(ctx *gin.Context, email, password string) (*dto.UserOutput, *exceptions.Exception)
 	RefreshToken(ctx *gin.Context, refreshToken string) (accessToken string, err *exceptions.Exception)
 	Logout(ctx *gin.Context, accessToken, refreshToken string) *exceptions.Exception
 	CreateVerificationToken(ctx *gin.Context, userUUID string) (*string, *exceptions.Exception)
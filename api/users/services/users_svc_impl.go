@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"xanny-go/api/users/dto"
 	"xanny-go/api/users/repositories"
 	"xanny-go/models"
@@ -38,13 +39,13 @@ func NewComponentServices(compRepositories repositories.CompRepositories, db *go
 func (s *CompServicesImpl) Create(ctx *gin.Context, data dto.Users) *exceptions.Exception {
 	validateErr := s.validate.Struct(data)
 	if validateErr != nil {
-		return exceptions.NewValidationException(validateErr)
+		return helpers.ValidationException(data, validateErr)
 	}
 
-	tx := s.DB.Begin()
+	tx := s.DB.WithContext(ctx).Begin()
 	defer helpers.CommitOrRollback(tx)
 
-	hashedPassword, err := helpers.HashPassword(data.Passoword)
+	hashedPassword, err := helpers.HashPassword(data.Password)
 	if err != nil {
 		return err
 	}
@@ -61,10 +62,15 @@ func (s *CompServicesImpl) Create(ctx *gin.Context, data dto.Users) *exceptions.
 		return err
 	}
 
+	// The request context is cancelled once the response is sent, so the email
+	// is sent on a copy that keeps its values but not its deadline.
+	background := ctx.Copy()
+	background.Request = background.Request.WithContext(context.WithoutCancel(ctx.Request.Context()))
+
 	go func() {
-		token, err := s.CreateVerificationToken(ctx, userUUID)
+		token, err := s.CreateVerificationToken(background, userUUID)
 		if err != nil {
-			logger.Error(err.Error())
+			logger.Error("Failed to create verification token: %s", err.Message)
 			return
 		}
 
@@ -75,7 +81,7 @@ func (s *CompServicesImpl) Create(ctx *gin.Context, data dto.Users) *exceptions.
 			SupportEmail:    "support@xanware.id",
 		})
 		if err != nil {
-			logger.Error(err.Error())
+			logger.Error("Failed to send verification email: %s", err.Message)
 			return
 		}
 	}()
@@ -100,7 +106,7 @@ func (s *CompServicesImpl) CreateVerificationToken(ctx *gin.Context, userUUID st
 }
 
 func (s *CompServicesImpl) ResendVerificationEmail(ctx *gin.Context, email string) *exceptions.Exception {
-	tx := s.DB.Begin()
+	tx := s.DB.WithContext(ctx).Begin()
 	defer helpers.CommitOrRollback(tx)
 
 	user, err := s.repo.FindByEmail(ctx, tx, email)
@@ -142,7 +148,7 @@ func (s *CompServicesImpl) ResendVerificationEmail(ctx *gin.Context, email strin
 }
 
 func (s *CompServicesImpl) VerificationEmail(ctx *gin.Context, token string) *exceptions.Exception {
-	tx := s.DB.Begin()
+	tx := s.DB.WithContext(ctx).Begin()
 	defer helpers.CommitOrRollback(tx)
 
 	tokenData, err := s.repo.FindVerificationToken(ctx, tx, token)
@@ -175,19 +181,11 @@ func (s *CompServicesImpl) VerificationEmail(ctx *gin.Context, token string) *ex
 }
 
 func (s *CompServicesImpl) Login(ctx *gin.Context, email, password string) (accessToken, refreshToken string, err *exceptions.Exception) {
-	user, err := s.repo.FindByEmail(ctx, s.DB, email)
+	user, err := s.Authenticate(ctx, email, password)
 	if err != nil {
 		return "", "", err
 	}
 
-	if hashErr := helpers.CheckPasswordHash(password, user.HashedPassword); hashErr != nil {
-		return "", "", exceptions.NewException(401, "Invalid email or password")
-	}
-
-	if !user.IsEmailVerified {
-		return "", "", exceptions.NewException(401, "Email is not verified")
-	}
-
 	jwtSecret := config.GetJWTSecret()
 	token := jwt.New(jwt.SigningMethodHS256)
 	claims := token.Claims.(jwt.MapClaims)
@@ -209,7 +207,7 @@ func (s *CompServicesImpl) Login(ctx *gin.Context, email, password string) (acce
 		ExpiresAt: refreshTokenExp,
 		CreatedAt: time.Now(),
 	}
-	tx := s.DB.Begin()
+	tx := s.DB.WithContext(ctx).Begin()
 	if err := s.repo.CreateRefreshToken(ctx, tx, refreshTokenModel); err != nil {
 		tx.Rollback()
 		return "", "", exceptions.NewException(500, "Failed to save refresh token")
@@ -219,6 +217,29 @@ func (s *CompServicesImpl) Login(ctx *gin.Context, email, password string) (acce
 	return accessTokenStr, refreshTokenRaw, nil
 }
 
+// Authenticate checks credentials without issuing tokens, for cookie sessions.
+func (s *CompServicesImpl) Authenticate(ctx *gin.Context, email, password string) (*dto.UserOutput, *exceptions.Exception) {
+	user, err := s.repo.FindByEmail(ctx, s.DB, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if hashErr := helpers.CheckPasswordHash(password, user.HashedPassword); hashErr != nil {
+		return nil, exceptions.NewException(401, "Invalid email or password")
+	}
+
+	if !user.IsEmailVerified {
+		return nil, exceptions.NewException(401, "Email is not verified")
+	}
+
+	return &dto.UserOutput{
+		UUID:            user.UUID,
+		Email:           user.Email,
+		IsEmailVerified: user.IsEmailVerified,
+		Name:            user.Name,
+	}, nil
+}
+
 func (s *CompServicesImpl) RefreshToken(ctx *gin.Context, refreshToken string) (accessToken string, err *exceptions.Exception) {
 	tokenModel, errFind := s.repo.FindRefreshToken(ctx, s.DB, refreshToken)
 	if errFind != nil {
@@ -250,7 +271,7 @@ func (s *CompServicesImpl) RefreshToken(ctx *gin.Context, refreshToken string) (
 }
 
 func (s *CompServicesImpl) Logout(ctx *gin.Context, accessToken, refreshToken string) *exceptions.Exception {
-	tx := s.DB.Begin()
+	tx := s.DB.WithContext(ctx).Begin()
 
 	claims := jwt.MapClaims{}
 	jwtSecret := config.GetJWTSecret()
@@ -259,7 +280,7 @@ func (s *CompServicesImpl) Logout(ctx *gin.Context, accessToken, refreshToken st
 	})
 	if err == nil {
 		exp, _ := claims["exp"].(float64)
-		helpers.SetBlacklistedToken(accessToken, time.Unix(int64(exp), 0))
+		helpers.SetBlacklistedToken(ctx, accessToken, time.Unix(int64(exp), 0))
 	}
 
 	s.repo.DeleteRefreshToken(ctx, tx, refreshToken)
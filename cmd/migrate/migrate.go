@@ -8,7 +8,7 @@ import (
 func main() {
 	db := config.InitDB()
 
-	err := db.AutoMigrate(&models.Users{}, &models.Clients{}, &models.RefreshToken{}, &models.BlacklistedToken{})
+	err := db.AutoMigrate(&models.Users{}, &models.Clients{}, &models.ClientDailyRollup{}, &models.RefreshToken{}, &models.BlacklistedToken{})
 	if err != nil {
 		panic("failed to migrate models: " + err.Error())
 	}
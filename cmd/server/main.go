@@ -29,7 +29,10 @@ import (
 	"syscall"
 	"time"
 	"xanny-go/docs"
+	"xanny-go/pkg/cache"
 	"xanny-go/pkg/config"
+	"xanny-go/pkg/helpers"
+	"xanny-go/pkg/jobs"
 	"xanny-go/pkg/logger"
 	"xanny-go/pkg/middleware"
 	"xanny-go/routers"
@@ -38,14 +41,18 @@ import (
 
 	"github.com/didip/tollbooth/v7"
 	"github.com/didip/tollbooth/v7/limiter"
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
 
 func main() {
 	config.InitConfig()
-	config.InitRedis()
+	if err := config.InitRedis(); err != nil {
+		if config.RedisClient == nil {
+			logger.PanicError("%s", err.Message)
+		}
+		logger.Warning("%s", err.Message)
+	}
 	docs.SwaggerInfo.BasePath = "/api"
 
 	logger.Startup()
@@ -53,29 +60,77 @@ func main() {
 	environment := config.GetEnvironment()
 
 	r := gin.New()
+	r.ContextWithFallback = true
 	r.Use(middleware.RequestResponseLogger())
+	r.Use(middleware.SecurityHeadersMiddleware(config.GetSecurityHeadersConfig()))
+
+	corsPolicy := config.GetCORSPolicy()
+	if err := corsPolicy.Validate(config.IsProduction()); err != nil {
+		logger.PanicError("Invalid CORS config: %v", err)
+	}
 
-	corsConfig := cors.DefaultConfig()
-	corsConfig.AllowOrigins = []string{"*"}
-	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"*"}
-	corsConfig.ExposeHeaders = []string{"Content-Length"}
-	corsConfig.AllowCredentials = true
-	r.Use(cors.New(corsConfig))
+	internalCORSPolicy := corsPolicy
+	internalCORSPolicy.AllowCredentials = false
+	r.Use(middleware.CORSMiddleware(corsPolicy, middleware.CORSOverride{
+		PathPrefix: "/internal",
+		Policy:     internalCORSPolicy,
+	}))
 
 	db := config.InitDB()
+	queryCache := cache.NewRedisCache(config.RedisClient, &cache.CacheOptions{DefaultTTL: config.GetQueryCacheTTL(), Prefix: "query:"})
+	if err := db.Use(cache.NewQueryCachePlugin(queryCache, &cache.QueryCacheOptions{NegativeTTL: config.GetQueryCacheNegativeTTL()})); err != nil {
+		logger.PanicError("Failed to register query cache: %v", err)
+	}
+
+	appCache, err := cache.NewCache(cache.AppCacheConfig())
+	if err != nil {
+		logger.PanicError("Failed to initialize cache: %v", err)
+	}
+	appCache = cache.Instrument("app", appCache)
+
+	warmupCtx, cancelWarmup := context.WithTimeout(context.Background(), time.Minute)
+	if err := cache.RunWarmups(warmupCtx, appCache, cache.DefaultWarmupOptions()); err != nil {
+		logger.Warning("Cache warm-up incomplete: %v", err)
+	}
+	cancelWarmup()
+
 	validate := validator.New(validator.WithRequiredStructEnabled())
 	lmt := tollbooth.NewLimiter(5, &limiter.ExpirableOptions{DefaultExpirationTTL: time.Second})
 
-	r.Use(middleware.ClientTracker(db))
-	r.Use(middleware.GzipResponseMiddleware())
+	anonymizeIP, err := helpers.IPAnonymizer(config.GetClientIPAnonymization(), config.GetClientIPHashKey())
+	if err != nil {
+		logger.PanicError("Invalid client tracking config: %v", err)
+	}
+
+	trackerOptions := middleware.DefaultClientTrackerOptions()
+	trackerOptions.AnonymizeIP = anonymizeIP
+	clientTracker := middleware.NewClientTracker(db, trackerOptions)
+	r.Use(clientTracker.Middleware())
+
+	retentionOptions := jobs.ClientRetentionOptions{
+		RetentionDays: config.GetClientRetentionDays(),
+		Mode:          config.GetClientRetentionMode(),
+		Interval:      config.GetClientRetentionInterval(),
+		Locker:        cache.NewRedisLocker(config.RedisClient, nil),
+	}
+	if err := retentionOptions.Validate(); err != nil {
+		logger.PanicError("Invalid client retention config: %v", err)
+	}
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	jobs.StartClientRetention(jobsCtx, db, retentionOptions)
+	r.Use(middleware.CompressionMiddleware(middleware.DefaultCompressionOptions()))
 	r.Use(middleware.RateLimitMiddleware(lmt))
+	r.Use(middleware.BodyLimitMiddleware(config.GetHTTPMaxBodyBytes()))
+
+	requestTimeout := middleware.TimeoutMiddleware(config.GetRequestTimeout())
 
-	internal := r.Group("/internal")
-	internalRouters.InternalRouters(internal, db, validate)
+	internal := r.Group("/internal", requestTimeout)
+	internalRouters.InternalRouters(internal, db, validate, appCache, clientTracker)
 
-	api := r.Group("/api")
-	routers.CompRouters(api, db, validate)
+	api := r.Group("/api", requestTimeout)
+	routers.CompRouters(api, db, validate, appCache)
 
 	var host string
 	switch environment {
@@ -89,9 +144,17 @@ func main() {
 
 	server := host + ":" + port
 
+	if config.GetHTTPWriteTimeout() > 0 && config.GetHTTPWriteTimeout() <= config.GetRequestTimeout() {
+		logger.Warning("HTTP_WRITE_TIMEOUT should be longer than REQUEST_TIMEOUT so timed out requests can still be answered")
+	}
+
 	srv := &http.Server{
-		Addr:    server,
-		Handler: r,
+		Addr:              server,
+		Handler:           r,
+		ReadTimeout:       config.GetHTTPReadTimeout(),
+		ReadHeaderTimeout: config.GetHTTPReadHeaderTimeout(),
+		WriteTimeout:      config.GetHTTPWriteTimeout(),
+		IdleTimeout:       config.GetHTTPIdleTimeout(),
 	}
 
 	serverErrors := make(chan error, 1)
@@ -110,6 +173,7 @@ func main() {
 
 	case sig := <-shutdown:
 		log.Printf("Start shutdown... Signal: %v", sig)
+		stopJobs()
 
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
@@ -120,6 +184,14 @@ func main() {
 				log.Fatalf("Could not force close server: %v", err)
 			}
 		}
+
+		if err := clientTracker.Close(ctx); err != nil {
+			log.Printf("Could not flush client tracker: %v", err)
+		}
+
+		if err := appCache.Close(); err != nil {
+			log.Printf("Could not close cache: %v", err)
+		}
 	}
 
 	log.Println("Server stopped")
@@ -1,9 +1,10 @@
-package cache
+package main
 
 import (
 	"context"
 	"log"
 	"time"
+	"xanny-go/pkg/cache"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -12,11 +13,11 @@ import (
 // IntegrationExample shows how to integrate cache with existing project
 type IntegrationExample struct {
 	db           *gorm.DB
-	cacheService *CacheService
+	cacheService *cache.CacheService
 }
 
 // NewIntegrationExample creates a new integration example
-func NewIntegrationExample(db *gorm.DB, cacheService *CacheService) *IntegrationExample {
+func NewIntegrationExample(db *gorm.DB, cacheService *cache.CacheService) *IntegrationExample {
 	return &IntegrationExample{
 		db:           db,
 		cacheService: cacheService,
@@ -24,39 +25,29 @@ func NewIntegrationExample(db *gorm.DB, cacheService *CacheService) *Integration
 }
 
 // SetupCacheIntegration demonstrates how to integrate cache with existing services
-func SetupCacheIntegration(db *gorm.DB, router *gin.Engine) error {
-	// 1. Initialize cache from environment
-	cache, err := NewCacheFromEnv()
-	if err != nil {
-		log.Printf("Warning: Failed to initialize cache: %v", err)
-		log.Println("Continuing without cache...")
-		return nil
-	}
-	defer cache.Close()
+func SetupCacheIntegration(db *gorm.DB, router *gin.Engine, c cache.Cache) {
+	// 1. Create cache service
+	cacheService := cache.NewCacheService(c)
 
-	// 2. Create cache service
-	cacheService := NewCacheService(cache)
-
-	// 3. Create integration example
+	// 2. Create integration example
 	integration := NewIntegrationExample(db, cacheService)
 
-	// 4. Setup cache routes
+	// 3. Setup demo routes
 	api := router.Group("/api")
 	SetupCacheRoutes(api, cacheService)
 
-	// 5. Setup cache middleware for public routes
+	// 4. Setup cache middleware for public routes
 	SetupCacheMiddleware(router, cacheService)
 
-	// 6. Add cache health check endpoint
+	// 5. Add cache health check endpoint
 	router.GET("/health/cache", integration.CacheHealthHandler)
 
 	log.Println("Cache integration setup completed")
-	return nil
 }
 
 // CacheHealthHandler handles cache health checks
 func (ie *IntegrationExample) CacheHealthHandler(c *gin.Context) {
-	healthChecker := NewCacheHealthChecker(ie.cacheService.cache)
+	healthChecker := cache.NewCacheHealthChecker(ie.cacheService.Cache())
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
@@ -83,11 +74,11 @@ func (ie *IntegrationExample) CacheHealthHandler(c *gin.Context) {
 // ExampleUserServiceWithCache demonstrates how to add cache to existing user service
 type ExampleUserServiceWithCache struct {
 	db           *gorm.DB
-	cacheService *CacheService
+	cacheService *cache.CacheService
 }
 
 // NewExampleUserServiceWithCache creates a new user service with cache
-func NewExampleUserServiceWithCache(db *gorm.DB, cacheService *CacheService) *ExampleUserServiceWithCache {
+func NewExampleUserServiceWithCache(db *gorm.DB, cacheService *cache.CacheService) *ExampleUserServiceWithCache {
 	return &ExampleUserServiceWithCache{
 		db:           db,
 		cacheService: cacheService,
@@ -99,7 +90,7 @@ func (us *ExampleUserServiceWithCache) GetUserWithCache(ctx context.Context, use
 	var user map[string]interface{}
 
 	cacheKey := "user:" + userID
-	err := us.cacheService.helper.GetOrSetJSON(ctx, cacheKey, &user, func() (interface{}, time.Duration, error) {
+	err := us.cacheService.Helper().GetOrSetJSON(ctx, cacheKey, &user, func() (interface{}, time.Duration, error) {
 		// This would be your actual database query
 		// For example:
 		// var userModel models.User
@@ -116,7 +107,7 @@ func (us *ExampleUserServiceWithCache) GetUserWithCache(ctx context.Context, use
 		}
 
 		return user, 10 * time.Minute, nil
-	})
+	}, "user:"+userID)
 
 	if err != nil {
 		return nil, err
@@ -134,28 +125,22 @@ func (us *ExampleUserServiceWithCache) UpdateUserWithCache(ctx context.Context,
 	//     return result.Error
 	// }
 
-	// Invalidate user cache
-	cacheKey := "user:" + userID
-	if err := us.cacheService.cache.Delete(ctx, cacheKey); err != nil {
+	// Invalidate every entry tagged with this user, whatever its key
+	if err := us.cacheService.Helper().InvalidateTags(ctx, "user:"+userID, "users"); err != nil {
 		log.Printf("Warning: Failed to invalidate user cache: %v", err)
 	}
 
-	// Invalidate user list caches
-	if err := us.cacheService.cache.InvalidatePattern(ctx, "users:*"); err != nil {
-		log.Printf("Warning: Failed to invalidate user list cache: %v", err)
-	}
-
 	return nil
 }
 
 // ExampleProductServiceWithCache demonstrates product service with different TTL strategies
 type ExampleProductServiceWithCache struct {
 	db           *gorm.DB
-	cacheService *CacheService
+	cacheService *cache.CacheService
 }
 
 // NewExampleProductServiceWithCache creates a new product service with cache
-func NewExampleProductServiceWithCache(db *gorm.DB, cacheService *CacheService) *ExampleProductServiceWithCache {
+func NewExampleProductServiceWithCache(db *gorm.DB, cacheService *cache.CacheService) *ExampleProductServiceWithCache {
 	return &ExampleProductServiceWithCache{
 		db:           db,
 		cacheService: cacheService,
@@ -167,7 +152,7 @@ func (ps *ExampleProductServiceWithCache) GetProductWithCache(ctx context.Contex
 	var product map[string]interface{}
 
 	cacheKey := "product:" + productID
-	err := ps.cacheService.helper.GetOrSetJSON(ctx, cacheKey, &product, func() (interface{}, time.Duration, error) {
+	err := ps.cacheService.Helper().GetOrSetJSON(ctx, cacheKey, &product, func() (interface{}, time.Duration, error) {
 		// Simulate database query for product details
 		product = map[string]interface{}{
 			"id":          productID,
@@ -192,7 +177,7 @@ func (ps *ExampleProductServiceWithCache) GetProductPriceWithCache(ctx context.C
 	var price float64
 
 	cacheKey := "product_price:" + productID
-	err := ps.cacheService.helper.GetOrSetJSON(ctx, cacheKey, &price, func() (interface{}, time.Duration, error) {
+	err := ps.cacheService.Helper().GetOrSetJSON(ctx, cacheKey, &price, func() (interface{}, time.Duration, error) {
 		// Simulate database query for price
 		price = 99.99
 
@@ -209,11 +194,11 @@ func (ps *ExampleProductServiceWithCache) GetProductPriceWithCache(ctx context.C
 
 // ExampleConfigurationService demonstrates configuration caching
 type ExampleConfigurationService struct {
-	cacheService *CacheService
+	cacheService *cache.CacheService
 }
 
 // NewExampleConfigurationService creates a new configuration service with cache
-func NewExampleConfigurationService(cacheService *CacheService) *ExampleConfigurationService {
+func NewExampleConfigurationService(cacheService *cache.CacheService) *ExampleConfigurationService {
 	return &ExampleConfigurationService{
 		cacheService: cacheService,
 	}
@@ -224,7 +209,7 @@ func (cs *ExampleConfigurationService) GetConfigurationWithCache(ctx context.Con
 	var config map[string]interface{}
 
 	cacheKey := "config:" + configKey
-	err := cs.cacheService.helper.GetOrSetJSON(ctx, cacheKey, &config, func() (interface{}, time.Duration, error) {
+	err := cs.cacheService.Helper().GetOrSetJSON(ctx, cacheKey, &config, func() (interface{}, time.Duration, error) {
 		// Simulate configuration loading
 		config = map[string]interface{}{
 			"key":          configKey,
@@ -247,16 +232,16 @@ func (cs *ExampleConfigurationService) GetConfigurationWithCache(ctx context.Con
 func (cs *ExampleConfigurationService) RefreshConfiguration(ctx context.Context, configKey string) error {
 	// Invalidate configuration cache
 	cacheKey := "config:" + configKey
-	return cs.cacheService.cache.Delete(ctx, cacheKey)
+	return cs.cacheService.Cache().Delete(ctx, cacheKey)
 }
 
 // ExampleRateLimitService demonstrates rate limiting with cache
 type ExampleRateLimitService struct {
-	cacheService *CacheService
+	cacheService *cache.CacheService
 }
 
 // NewExampleRateLimitService creates a new rate limit service with cache
-func NewExampleRateLimitService(cacheService *CacheService) *ExampleRateLimitService {
+func NewExampleRateLimitService(cacheService *cache.CacheService) *ExampleRateLimitService {
 	return &ExampleRateLimitService{
 		cacheService: cacheService,
 	}
@@ -265,7 +250,7 @@ func NewExampleRateLimitService(cacheService *CacheService) *ExampleRateLimitSer
 // CheckRateLimit demonstrates rate limiting with cache
 func (rls *ExampleRateLimitService) CheckRateLimit(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
 	// Try to increment the counter
-	if redisCache, ok := rls.cacheService.cache.(*RedisCache); ok {
+	if redisCache, ok := rls.cacheService.Cache().(*cache.RedisCache); ok {
 		current, err := redisCache.Increment(ctx, "rate_limit:"+key, 1)
 		if err != nil {
 			return false, err
@@ -285,11 +270,11 @@ func (rls *ExampleRateLimitService) CheckRateLimit(ctx context.Context, key stri
 
 // ExampleSessionService demonstrates session management with cache
 type ExampleSessionService struct {
-	cacheService *CacheService
+	cacheService *cache.CacheService
 }
 
 // NewExampleSessionService creates a new session service with cache
-func NewExampleSessionService(cacheService *CacheService) *ExampleSessionService {
+func NewExampleSessionService(cacheService *cache.CacheService) *ExampleSessionService {
 	return &ExampleSessionService{
 		cacheService: cacheService,
 	}
@@ -298,7 +283,7 @@ func NewExampleSessionService(cacheService *CacheService) *ExampleSessionService
 // StoreSession demonstrates session storage with cache
 func (ss *ExampleSessionService) StoreSession(ctx context.Context, sessionID string, data map[string]interface{}) error {
 	cacheKey := "session:" + sessionID
-	return ss.cacheService.helper.SetJSON(ctx, cacheKey, data, 30*time.Minute)
+	return ss.cacheService.Helper().SetJSON(ctx, cacheKey, data, 30*time.Minute)
 }
 
 // GetSession demonstrates session retrieval with cache
@@ -306,7 +291,7 @@ func (ss *ExampleSessionService) GetSession(ctx context.Context, sessionID strin
 	var session map[string]interface{}
 
 	cacheKey := "session:" + sessionID
-	err := ss.cacheService.helper.GetJSON(ctx, cacheKey, &session)
+	err := ss.cacheService.Helper().GetJSON(ctx, cacheKey, &session)
 	if err != nil {
 		return nil, err
 	}
@@ -317,5 +302,5 @@ func (ss *ExampleSessionService) GetSession(ctx context.Context, sessionID strin
 // DeleteSession demonstrates session deletion
 func (ss *ExampleSessionService) DeleteSession(ctx context.Context, sessionID string) error {
 	cacheKey := "session:" + sessionID
-	return ss.cacheService.cache.Delete(ctx, cacheKey)
+	return ss.cacheService.Cache().Delete(ctx, cacheKey)
 }
@@ -1,23 +1,50 @@
-package cache
+// Command cache demonstrates pkg/cache with fake user and product data. Run
+// it with CACHE_TYPE=memory to try it without Redis:
+//
+//	CACHE_TYPE=memory go run ./examples/cache
+package main
 
 import (
 	"context"
 	"fmt"
 	"log"
 	"time"
+	"xanny-go/pkg/cache"
+	appconfig "xanny-go/pkg/config"
+
+	"github.com/gin-gonic/gin"
 )
 
+func main() {
+	Example()
+	ExampleWithMemory()
+	ExampleWithHealthCheck()
+
+	c, err := cache.NewCacheFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	router := gin.Default()
+	SetupCacheIntegration(nil, router, c)
+
+	if err := router.Run(":8081"); err != nil {
+		log.Fatal(err)
+	}
+}
+
 // Example demonstrates how to use the cache layer
 func Example() {
 	// 1. Create cache from environment variables
-	cache, err := NewCacheFromEnv()
+	c, err := cache.NewCacheFromEnv()
 	if err != nil {
 		log.Fatalf("Failed to create cache: %v", err)
 	}
-	defer cache.Close()
+	defer c.Close()
 
 	// 2. Create cache service
-	cacheService := NewCacheService(cache)
+	cacheService := cache.NewCacheService(c)
 
 	// 3. Create user service with cache
 	userService := NewUserService(cacheService)
@@ -61,29 +88,31 @@ func Example() {
 // ExampleWithRedis demonstrates Redis cache usage
 func ExampleWithRedis() {
 	// Create Redis cache configuration
-	config := &CacheConfig{
-		Type:       CacheTypeRedis,
-		RedisAddr:  "localhost:6379",
-		RedisDB:    0,
+	config := &cache.CacheConfig{
+		Type: cache.CacheTypeRedis,
+		RedisOptions: &appconfig.RedisOptions{
+			Addrs: []string{"localhost:6379"},
+			DB:    0,
+		},
 		DefaultTTL: 10 * time.Minute,
 		Prefix:     "app:",
 	}
 
 	// Create cache
-	cache, err := NewCache(config)
+	c, err := cache.NewCache(config)
 	if err != nil {
 		log.Fatalf("Failed to create Redis cache: %v", err)
 	}
-	defer cache.Close()
+	defer c.Close()
 
 	// Create cache service
-	cacheService := NewCacheService(cache)
+	cacheService := cache.NewCacheService(c)
 
 	// Example operations
 	ctx := context.Background()
 
 	// Set a value
-	err = cacheService.helper.SetJSON(ctx, "test_key", map[string]interface{}{
+	err = cacheService.Helper().SetJSON(ctx, "test_key", map[string]interface{}{
 		"message": "Hello from Redis!",
 		"time":    time.Now(),
 	}, 5*time.Minute)
@@ -93,7 +122,7 @@ func ExampleWithRedis() {
 
 	// Get the value
 	var result map[string]interface{}
-	err = cacheService.helper.GetJSON(ctx, "test_key", &result)
+	err = cacheService.Helper().GetJSON(ctx, "test_key", &result)
 	if err != nil {
 		log.Printf("Error getting value: %v", err)
 	} else {
@@ -101,7 +130,7 @@ func ExampleWithRedis() {
 	}
 
 	// Check if key exists
-	exists, err := cache.Exists(ctx, "test_key")
+	exists, err := c.Exists(ctx, "test_key")
 	if err != nil {
 		log.Printf("Error checking existence: %v", err)
 	} else {
@@ -109,7 +138,7 @@ func ExampleWithRedis() {
 	}
 
 	// Delete the key
-	err = cache.Delete(ctx, "test_key")
+	err = c.Delete(ctx, "test_key")
 	if err != nil {
 		log.Printf("Error deleting key: %v", err)
 	}
@@ -118,19 +147,19 @@ func ExampleWithRedis() {
 // ExampleWithMemory demonstrates in-memory cache usage
 func ExampleWithMemory() {
 	// Create memory cache configuration
-	config := &CacheConfig{
-		Type:       CacheTypeMemory,
+	config := &cache.CacheConfig{
+		Type:       cache.CacheTypeMemory,
 		DefaultTTL: 5 * time.Minute,
 		MaxSize:    1000,
 		Prefix:     "mem:",
 	}
 
 	// Create cache
-	cache, err := NewCache(config)
+	c, err := cache.NewCache(config)
 	if err != nil {
 		log.Fatalf("Failed to create memory cache: %v", err)
 	}
-	defer cache.Close()
+	defer c.Close()
 
 	// Example operations
 	ctx := context.Background()
@@ -143,7 +172,7 @@ func ExampleWithMemory() {
 	}
 
 	for key, value := range data {
-		err := cache.Set(ctx, key, value, 1*time.Minute)
+		err := c.Set(ctx, key, value, 1*time.Minute)
 		if err != nil {
 			log.Printf("Error setting %s: %v", key, err)
 		}
@@ -151,7 +180,7 @@ func ExampleWithMemory() {
 
 	// Get multiple values
 	keys := []string{"key1", "key2", "key3"}
-	if redisCache, ok := cache.(*RedisCache); ok {
+	if redisCache, ok := c.(*cache.RedisCache); ok {
 		results, err := redisCache.GetMultiple(ctx, keys)
 		if err != nil {
 			log.Printf("Error getting multiple values: %v", err)
@@ -161,7 +190,7 @@ func ExampleWithMemory() {
 	}
 
 	// Get cache statistics
-	if memoryCache, ok := cache.(*MemoryCache); ok {
+	if memoryCache, ok := c.(*cache.MemoryCache); ok {
 		stats := memoryCache.GetStats()
 		fmt.Printf("Memory cache stats: %+v\n", stats)
 	}
@@ -170,7 +199,7 @@ func ExampleWithMemory() {
 // ExampleWithCacheManager demonstrates cache manager usage
 func ExampleWithCacheManager() {
 	// Create cache manager
-	manager, err := NewCacheManagerFromEnv()
+	manager, err := cache.NewCacheManagerFromEnv()
 	if err != nil {
 		log.Fatalf("Failed to create cache manager: %v", err)
 	}
@@ -221,14 +250,14 @@ func ExampleWithCacheManager() {
 // ExampleWithHealthCheck demonstrates cache health checking
 func ExampleWithHealthCheck() {
 	// Create cache
-	cache, err := NewCacheFromEnv()
+	c, err := cache.NewCacheFromEnv()
 	if err != nil {
 		log.Fatalf("Failed to create cache: %v", err)
 	}
-	defer cache.Close()
+	defer c.Close()
 
 	// Create health checker
-	healthChecker := NewCacheHealthChecker(cache)
+	healthChecker := cache.NewCacheHealthChecker(c)
 
 	// Check health
 	ctx := context.Background()
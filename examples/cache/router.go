@@ -0,0 +1,185 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"xanny-go/pkg/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupCacheRoutes sets up the demo routes next to the cache admin routes
+func SetupCacheRoutes(router *gin.RouterGroup, cacheService *cache.CacheService) {
+	admin := cache.NewCacheController(cacheService)
+	controller := NewDemoController(cacheService)
+
+	// Cache management routes; the server mounts these under /internal
+	cacheGroup := router.Group("/cache")
+	{
+		cacheGroup.GET("/stats", admin.GetCacheStats)
+		cacheGroup.GET("/metrics", admin.GetCacheMetrics)
+		cacheGroup.POST("/flush", admin.FlushCache)
+		cacheGroup.POST("/invalidate", admin.InvalidatePattern)
+		cacheGroup.POST("/set", admin.SetCacheValue)
+		cacheGroup.GET("/:key", admin.GetCacheValue)
+		cacheGroup.DELETE("/:key", admin.DeleteCacheValue)
+	}
+
+	// Example service routes with caching
+	usersGroup := router.Group("/users")
+	{
+		usersGroup.GET("", controller.GetUsers)
+		usersGroup.GET("/:id", controller.GetUser)
+		usersGroup.PUT("/:id", controller.UpdateUser)
+	}
+
+	productsGroup := router.Group("/products")
+	{
+		productsGroup.GET("/:id", controller.GetProduct)
+		productsGroup.GET("/:id/price", controller.GetProductPrice)
+	}
+}
+
+// SetupCacheMiddleware sets up cache middleware for routes
+func SetupCacheMiddleware(router *gin.Engine, cacheService *cache.CacheService) {
+	// Add cache middleware to specific routes
+	cacheMiddleware := cache.CacheMiddleware(&cache.CacheMiddlewareOptions{
+		Cache:      cacheService.Cache(),
+		DefaultTTL: 5 * time.Minute,
+		SkipCache: func(c *gin.Context) bool {
+			// Skip caching for authenticated requests
+			return c.GetHeader("Authorization") != ""
+		},
+	})
+
+	// Apply cache middleware to public routes
+	publicGroup := router.Group("/api/public")
+	publicGroup.Use(cacheMiddleware)
+	{
+		// Add public routes here that should be cached
+	}
+
+	// Add cache control headers middleware
+	cacheControlMiddleware := cache.CacheControlMiddleware(10*time.Minute, true)
+	router.Use(cacheControlMiddleware)
+}
+
+// DemoController serves the demo user and product services
+type DemoController struct {
+	userService    *UserService
+	productService *ProductService
+}
+
+// NewDemoController creates a demo controller on cacheService
+func NewDemoController(cacheService *cache.CacheService) *DemoController {
+	return &DemoController{
+		userService:    NewUserService(cacheService),
+		productService: NewProductService(cacheService),
+	}
+}
+
+// GetUser handles GET /users/:id
+func (dc *DemoController) GetUser(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	user, err := dc.userService.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   user,
+		"cached": true, // This would be determined by cache hit/miss
+	})
+}
+
+// GetUsers handles GET /users
+func (dc *DemoController) GetUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	users, err := dc.userService.GetUsers(c.Request.Context(), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": users,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+		},
+	})
+}
+
+// UpdateUser handles PUT /users/:id
+func (dc *DemoController) UpdateUser(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	var updates map[string]interface{}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := dc.userService.UpdateUser(c.Request.Context(), userID, updates)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "User updated successfully",
+		"cache_invalidated": true,
+	})
+}
+
+// GetProduct handles GET /products/:id
+func (dc *DemoController) GetProduct(c *gin.Context) {
+	productID := c.Param("id")
+	if productID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "product ID is required"})
+		return
+	}
+
+	product, err := dc.productService.GetProduct(c.Request.Context(), productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": product,
+	})
+}
+
+// GetProductPrice handles GET /products/:id/price
+func (dc *DemoController) GetProductPrice(c *gin.Context) {
+	productID := c.Param("id")
+	if productID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "product ID is required"})
+		return
+	}
+
+	price, err := dc.productService.GetProductPrice(c.Request.Context(), productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product_id": productID,
+		"price":      price,
+	})
+}
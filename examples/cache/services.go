@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"xanny-go/pkg/cache"
+)
+
+// User represents a user entity
+type User struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Email    string    `json:"email"`
+	Created  time.Time `json:"created"`
+	Modified time.Time `json:"modified"`
+}
+
+// UserService demonstrates how to use cache in a service layer
+type UserService struct {
+	cacheService *cache.CacheService
+	// In a real application, you would have a repository here
+	// userRepo UserRepository
+}
+
+// NewUserService creates a new user service with cache
+func NewUserService(cacheService *cache.CacheService) *UserService {
+	return &UserService{
+		cacheService: cacheService,
+	}
+}
+
+// GetUser retrieves a user with caching
+func (us *UserService) GetUser(ctx context.Context, userID string) (*User, error) {
+	var user User
+
+	// Try to get from cache first
+	cacheKey := fmt.Sprintf("user:%s", userID)
+	err := us.cacheService.Helper().GetOrSetJSON(ctx, cacheKey, &user, func() (interface{}, time.Duration, error) {
+		// This function is called when cache miss occurs
+		// In a real application, you would fetch from database here
+		// user, err := us.userRepo.FindByID(ctx, userID)
+
+		// Simulate database fetch
+		user = User{
+			ID:       userID,
+			Name:     "John Doe",
+			Email:    "john@example.com",
+			Created:  time.Now().Add(-24 * time.Hour),
+			Modified: time.Now(),
+		}
+
+		// Return the user, cache TTL, and any error
+		return user, 10 * time.Minute, nil
+	}, "user:"+userID)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return &user, nil
+}
+
+// UpdateUser updates a user and invalidates cache
+func (us *UserService) UpdateUser(ctx context.Context, userID string, updates map[string]interface{}) error {
+	// In a real application, you would update the database here
+	// err := us.userRepo.Update(ctx, userID, updates)
+
+	// Invalidate every entry built from this user, including the user lists
+	if err := us.cacheService.Helper().InvalidateTags(ctx, "user:"+userID, "users"); err != nil {
+		// Log error but don't fail the operation
+		fmt.Printf("Failed to invalidate cache for user %s: %v\n", userID, err)
+	}
+
+	return nil
+}
+
+// GetUsers retrieves a list of users with caching
+func (us *UserService) GetUsers(ctx context.Context, page, limit int) ([]User, error) {
+	var users []User
+
+	cacheKey := fmt.Sprintf("users:page:%d:limit:%d", page, limit)
+	err := us.cacheService.Helper().GetOrSetJSON(ctx, cacheKey, &users, func() (interface{}, time.Duration, error) {
+		// Simulate database fetch
+		users = []User{
+			{ID: "1", Name: "John Doe", Email: "john@example.com", Created: time.Now().Add(-24 * time.Hour), Modified: time.Now()},
+			{ID: "2", Name: "Jane Smith", Email: "jane@example.com", Created: time.Now().Add(-12 * time.Hour), Modified: time.Now()},
+		}
+
+		return users, 5 * time.Minute, nil
+	}, "users")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	return users, nil
+}
+
+// Product represents a product entity
+type Product struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Price       float64 `json:"price"`
+	Description string  `json:"description"`
+	Category    string  `json:"category"`
+}
+
+// ProductService demonstrates cache with different TTL strategies
+type ProductService struct {
+	cacheService *cache.CacheService
+}
+
+// NewProductService creates a new product service with cache
+func NewProductService(cacheService *cache.CacheService) *ProductService {
+	return &ProductService{
+		cacheService: cacheService,
+	}
+}
+
+// GetProduct retrieves a product with long-term caching
+func (ps *ProductService) GetProduct(ctx context.Context, productID string) (*Product, error) {
+	var product Product
+
+	cacheKey := fmt.Sprintf("product:%s", productID)
+	err := ps.cacheService.Helper().GetOrSetJSON(ctx, cacheKey, &product, func() (interface{}, time.Duration, error) {
+		// Simulate database fetch
+		product = Product{
+			ID:          productID,
+			Name:        "Sample Product",
+			Price:       99.99,
+			Description: "A sample product description",
+			Category:    "Electronics",
+		}
+
+		// Products can be cached longer since they don't change frequently
+		return product, 1 * time.Hour, nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+
+	return &product, nil
+}
+
+// GetProductPrice retrieves just the price with short-term caching
+func (ps *ProductService) GetProductPrice(ctx context.Context, productID string) (float64, error) {
+	var price float64
+
+	cacheKey := fmt.Sprintf("product_price:%s", productID)
+	err := ps.cacheService.Helper().GetOrSetJSON(ctx, cacheKey, &price, func() (interface{}, time.Duration, error) {
+		// Simulate database fetch
+		price = 99.99
+
+		// Prices can change frequently, so cache for shorter time
+		return price, 5 * time.Minute, nil
+	})
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to get product price: %w", err)
+	}
+
+	return price, nil
+}
@@ -9,6 +9,7 @@ import (
 	userControllers "xanny-go/api/users/controllers"
 	userRepositories "xanny-go/api/users/repositories"
 	userServices "xanny-go/api/users/services"
+	"xanny-go/pkg/session"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/wire"
@@ -21,7 +22,7 @@ var userFeatureSet = wire.NewSet(
 	userControllers.NewCompController,
 )
 
-func InitializeUserController(db *gorm.DB, validate *validator.Validate) userControllers.CompControllers {
+func InitializeUserController(db *gorm.DB, validate *validator.Validate, sessions *session.Manager) userControllers.CompControllers {
 	wire.Build(userFeatureSet)
 	return nil
 }
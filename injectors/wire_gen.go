@@ -13,14 +13,15 @@ import (
 	"xanny-go/api/users/controllers"
 	"xanny-go/api/users/repositories"
 	"xanny-go/api/users/services"
+	"xanny-go/pkg/session"
 )
 
 // Injectors from injector.go:
 
-func InitializeUserController(db *gorm.DB, validate *validator.Validate) controllers.CompControllers {
+func InitializeUserController(db *gorm.DB, validate *validator.Validate, sessions *session.Manager) controllers.CompControllers {
 	compRepositories := repositories.NewComponentRepository()
 	compServices := services.NewComponentServices(compRepositories, db, validate)
-	compControllers := controllers.NewCompController(compServices)
+	compControllers := controllers.NewCompController(compServices, sessions)
 	return compControllers
 }
 
@@ -0,0 +1,12 @@
+package controllers
+
+import "github.com/gin-gonic/gin"
+
+type CompControllers interface {
+	RequestsOverTime(ctx *gin.Context)
+	TopEndpoints(ctx *gin.Context)
+	BrowserBreakdown(ctx *gin.Context)
+	OSBreakdown(ctx *gin.Context)
+	UniqueIPs(ctx *gin.Context)
+	TopReferrers(ctx *gin.Context)
+}
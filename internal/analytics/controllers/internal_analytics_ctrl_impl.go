@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"xanny-go/internal/analytics/dto"
+	"xanny-go/internal/analytics/services"
+	"xanny-go/pkg/exceptions"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CompControllersImpl struct {
+	services services.CompServices
+}
+
+func NewCompController(compServices services.CompServices) CompControllers {
+	return &CompControllersImpl{
+		services: compServices,
+	}
+}
+
+func (h *CompControllersImpl) RequestsOverTime(ctx *gin.Context) {
+	query, ok := bindQuery(ctx)
+	if !ok {
+		return
+	}
+
+	rows, err := h.services.RequestsOverTime(ctx, query)
+	if err != nil {
+		ctx.JSON(err.Status, err)
+		return
+	}
+
+	respond(ctx, query.Format, "requests", rows)
+}
+
+func (h *CompControllersImpl) TopEndpoints(ctx *gin.Context) {
+	query, ok := bindQuery(ctx)
+	if !ok {
+		return
+	}
+
+	rows, err := h.services.TopEndpoints(ctx, query)
+	if err != nil {
+		ctx.JSON(err.Status, err)
+		return
+	}
+
+	respond(ctx, query.Format, "endpoints", rows)
+}
+
+func (h *CompControllersImpl) BrowserBreakdown(ctx *gin.Context) {
+	query, ok := bindQuery(ctx)
+	if !ok {
+		return
+	}
+
+	rows, err := h.services.BrowserBreakdown(ctx, query)
+	if err != nil {
+		ctx.JSON(err.Status, err)
+		return
+	}
+
+	respond(ctx, query.Format, "browsers", rows)
+}
+
+func (h *CompControllersImpl) OSBreakdown(ctx *gin.Context) {
+	query, ok := bindQuery(ctx)
+	if !ok {
+		return
+	}
+
+	rows, err := h.services.OSBreakdown(ctx, query)
+	if err != nil {
+		ctx.JSON(err.Status, err)
+		return
+	}
+
+	respond(ctx, query.Format, "os", rows)
+}
+
+func (h *CompControllersImpl) UniqueIPs(ctx *gin.Context) {
+	query, ok := bindQuery(ctx)
+	if !ok {
+		return
+	}
+
+	row, err := h.services.UniqueIPs(ctx, query)
+	if err != nil {
+		ctx.JSON(err.Status, err)
+		return
+	}
+
+	respond(ctx, query.Format, "unique-ips", row)
+}
+
+func (h *CompControllersImpl) TopReferrers(ctx *gin.Context) {
+	query, ok := bindQuery(ctx)
+	if !ok {
+		return
+	}
+
+	rows, err := h.services.TopReferrers(ctx, query)
+	if err != nil {
+		ctx.JSON(err.Status, err)
+		return
+	}
+
+	respond(ctx, query.Format, "referrers", rows)
+}
+
+func bindQuery(ctx *gin.Context) (dto.Query, bool) {
+	var query dto.Query
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, exceptions.NewException(http.StatusBadRequest, exceptions.ErrBadRequest))
+		return query, false
+	}
+
+	if query.Format != "" && query.Format != "json" && query.Format != "csv" {
+		ctx.JSON(http.StatusBadRequest, exceptions.NewException(http.StatusBadRequest, "format must be json or csv"))
+		return query, false
+	}
+
+	return query, true
+}
+
+func respond(ctx *gin.Context, format, name string, data dto.CSVExportable) {
+	if format != "csv" {
+		ctx.JSON(http.StatusOK, dto.Response{
+			Status:  http.StatusOK,
+			Message: "success",
+			Body:    data,
+		})
+		return
+	}
+
+	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	ctx.Header("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+	ctx.Status(http.StatusOK)
+
+	records := data.CSVRecords()
+	for _, record := range records {
+		for i, cell := range record {
+			record[i] = escapeFormula(cell)
+		}
+	}
+
+	writer := csv.NewWriter(ctx.Writer)
+	writer.Write(data.CSVHeader())
+	writer.WriteAll(records)
+}
+
+// escapeFormula stops spreadsheets from evaluating client-controlled values
+// such as referrers and paths as formulas
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
@@ -0,0 +1,18 @@
+package dto
+
+import "time"
+
+type Query struct {
+	From     string `form:"from"`
+	To       string `form:"to"`
+	Interval string `form:"interval"`
+	Limit    int    `form:"limit"`
+	Format   string `form:"format"`
+}
+
+type Filter struct {
+	From     time.Time
+	To       time.Time
+	Interval string
+	Limit    int
+}
@@ -0,0 +1,118 @@
+package dto
+
+import (
+	"strconv"
+	"time"
+)
+
+type Response struct {
+	Status  int         `json:"status"`
+	Message string      `json:"message"`
+	Body    interface{} `json:"body,omitempty"`
+}
+
+type CSVExportable interface {
+	CSVHeader() []string
+	CSVRecords() [][]string
+}
+
+type TimeBucket struct {
+	Bucket    time.Time `json:"bucket"`
+	Requests  int64     `json:"requests"`
+	UniqueIPs int64     `json:"unique_ips"`
+}
+
+type TimeBuckets []TimeBucket
+
+func (t TimeBuckets) CSVHeader() []string {
+	return []string{"bucket", "requests", "unique_ips"}
+}
+
+func (t TimeBuckets) CSVRecords() [][]string {
+	records := make([][]string, 0, len(t))
+	for _, row := range t {
+		records = append(records, []string{
+			row.Bucket.UTC().Format(time.RFC3339),
+			strconv.FormatInt(row.Requests, 10),
+			strconv.FormatInt(row.UniqueIPs, 10),
+		})
+	}
+	return records
+}
+
+type EndpointCount struct {
+	Endpoint     string  `json:"endpoint"`
+	Requests     int64   `json:"requests"`
+	Errors       int64   `json:"errors"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+type EndpointCounts []EndpointCount
+
+func (e EndpointCounts) CSVHeader() []string {
+	return []string{"endpoint", "requests", "errors", "avg_latency_ms"}
+}
+
+func (e EndpointCounts) CSVRecords() [][]string {
+	records := make([][]string, 0, len(e))
+	for _, row := range e {
+		records = append(records, []string{
+			row.Endpoint,
+			strconv.FormatInt(row.Requests, 10),
+			strconv.FormatInt(row.Errors, 10),
+			strconv.FormatFloat(row.AvgLatencyMs, 'f', 2, 64),
+		})
+	}
+	return records
+}
+
+type Breakdown struct {
+	Name     string `json:"name"`
+	Requests int64  `json:"requests"`
+}
+
+type Breakdowns []Breakdown
+
+func (b Breakdowns) CSVHeader() []string {
+	return []string{"name", "requests"}
+}
+
+func (b Breakdowns) CSVRecords() [][]string {
+	records := make([][]string, 0, len(b))
+	for _, row := range b {
+		records = append(records, []string{row.Name, strconv.FormatInt(row.Requests, 10)})
+	}
+	return records
+}
+
+type UniqueIPs struct {
+	UniqueIPs int64 `json:"unique_ips"`
+	Requests  int64 `json:"requests"`
+}
+
+func (u UniqueIPs) CSVHeader() []string {
+	return []string{"unique_ips", "requests"}
+}
+
+func (u UniqueIPs) CSVRecords() [][]string {
+	return [][]string{{strconv.FormatInt(u.UniqueIPs, 10), strconv.FormatInt(u.Requests, 10)}}
+}
+
+type Referrer struct {
+	Referrer string `json:"referrer"`
+	Requests int64  `json:"requests"`
+}
+
+type Referrers []Referrer
+
+func (r Referrers) CSVHeader() []string {
+	return []string{"referrer", "requests"}
+}
+
+func (r Referrers) CSVRecords() [][]string {
+	records := make([][]string, 0, len(r))
+	for _, row := range r {
+		records = append(records, []string{row.Referrer, strconv.FormatInt(row.Requests, 10)})
+	}
+	return records
+}
@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"xanny-go/internal/analytics/dto"
+	"xanny-go/pkg/exceptions"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CompRepositories interface {
+	RequestsOverTime(ctx *gin.Context, tx *gorm.DB, filter dto.Filter) (dto.TimeBuckets, *exceptions.Exception)
+	TopEndpoints(ctx *gin.Context, tx *gorm.DB, filter dto.Filter) (dto.EndpointCounts, *exceptions.Exception)
+	BreakdownBy(ctx *gin.Context, tx *gorm.DB, column string, filter dto.Filter) (dto.Breakdowns, *exceptions.Exception)
+	UniqueIPs(ctx *gin.Context, tx *gorm.DB, filter dto.Filter) (*dto.UniqueIPs, *exceptions.Exception)
+	TopReferrers(ctx *gin.Context, tx *gorm.DB, filter dto.Filter) (dto.Referrers, *exceptions.Exception)
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"xanny-go/internal/analytics/dto"
+	"xanny-go/models"
+	"xanny-go/pkg/exceptions"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CompRepositoriesImpl struct {
+}
+
+func NewComponentRepository() CompRepositories {
+	return &CompRepositoriesImpl{}
+}
+
+// clientActivitySQL combines raw client rows with the daily rollups the
+// retention job leaves behind once raw rows expire. Rollup rows carry their
+// counts instead of an IP, so unique IPs from them are summed per group and
+// only approximate. chr(63) is the question mark, which Raw would otherwise
+// read as a placeholder.
+const clientActivitySQL = `
+SELECT created_at AS at, split_part(api, chr(63), 1) AS endpoint, browser, os, device, ip,
+	1 AS requests, CASE WHEN status_code >= 500 THEN 1 ELSE 0 END AS errors,
+	latency_ms AS latency_total, 0 AS rolled_up_ips
+FROM clients
+WHERE deleted_at IS NULL AND created_at >= @from AND created_at < @to
+UNION ALL
+SELECT day, endpoint, browser, os, device, NULL,
+	requests, errors, avg_latency_ms * requests, unique_ips
+FROM client_daily_rollups
+WHERE deleted_at IS NULL AND day >= @from AND day < @to`
+
+// activity selects raw and rolled-up client traffic in the filter window
+func (r *CompRepositoriesImpl) activity(ctx *gin.Context, tx *gorm.DB, filter dto.Filter) *gorm.DB {
+	rows := tx.Raw(clientActivitySQL, map[string]interface{}{"from": filter.From, "to": filter.To})
+	return tx.WithContext(ctx).Table("(?) AS activity", rows)
+}
+
+func (r *CompRepositoriesImpl) between(ctx *gin.Context, tx *gorm.DB, filter dto.Filter) *gorm.DB {
+	return tx.WithContext(ctx).Model(&models.Clients{}).Where("created_at >= ? AND created_at < ?", filter.From, filter.To)
+}
+
+func (r *CompRepositoriesImpl) RequestsOverTime(ctx *gin.Context, tx *gorm.DB, filter dto.Filter) (dto.TimeBuckets, *exceptions.Exception) {
+	var rows dto.TimeBuckets
+	err := r.activity(ctx, tx, filter).
+		Select("date_trunc(?, at) AS bucket, sum(requests)::bigint AS requests, (count(DISTINCT ip) + sum(rolled_up_ips))::bigint AS unique_ips", filter.Interval).
+		Group("bucket").
+		Order("bucket").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, exceptions.ParseGormError(nil, err)
+	}
+	return rows, nil
+}
+
+func (r *CompRepositoriesImpl) TopEndpoints(ctx *gin.Context, tx *gorm.DB, filter dto.Filter) (dto.EndpointCounts, *exceptions.Exception) {
+	var rows dto.EndpointCounts
+	err := r.activity(ctx, tx, filter).
+		Select("endpoint, sum(requests)::bigint AS requests, sum(errors)::bigint AS errors, coalesce(sum(latency_total) / nullif(sum(requests), 0), 0) AS avg_latency_ms").
+		Group("endpoint").
+		Order("requests DESC").
+		Limit(filter.Limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, exceptions.ParseGormError(nil, err)
+	}
+	return rows, nil
+}
+
+func (r *CompRepositoriesImpl) BreakdownBy(ctx *gin.Context, tx *gorm.DB, column string, filter dto.Filter) (dto.Breakdowns, *exceptions.Exception) {
+	var rows dto.Breakdowns
+	err := r.activity(ctx, tx, filter).
+		Select(column + " AS name, sum(requests)::bigint AS requests").
+		Group(column).
+		Order("requests DESC").
+		Limit(filter.Limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, exceptions.ParseGormError(nil, err)
+	}
+	return rows, nil
+}
+
+func (r *CompRepositoriesImpl) UniqueIPs(ctx *gin.Context, tx *gorm.DB, filter dto.Filter) (*dto.UniqueIPs, *exceptions.Exception) {
+	var row dto.UniqueIPs
+	err := r.activity(ctx, tx, filter).
+		Select("(count(DISTINCT ip) + coalesce(sum(rolled_up_ips), 0))::bigint AS unique_ips, coalesce(sum(requests), 0)::bigint AS requests").
+		Scan(&row).Error
+	if err != nil {
+		return nil, exceptions.ParseGormError(nil, err)
+	}
+	return &row, nil
+}
+
+// TopReferrers only sees raw rows; rollups do not keep referrers
+func (r *CompRepositoriesImpl) TopReferrers(ctx *gin.Context, tx *gorm.DB, filter dto.Filter) (dto.Referrers, *exceptions.Exception) {
+	var rows dto.Referrers
+	err := r.between(ctx, tx, filter).
+		Select("origin AS referrer, count(*) AS requests").
+		Where("origin <> ''").
+		Group("origin").
+		Order("requests DESC").
+		Limit(filter.Limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, exceptions.ParseGormError(nil, err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,17 @@
+package services
+
+import (
+	"xanny-go/internal/analytics/dto"
+	"xanny-go/pkg/exceptions"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CompServices interface {
+	RequestsOverTime(ctx *gin.Context, query dto.Query) (dto.TimeBuckets, *exceptions.Exception)
+	TopEndpoints(ctx *gin.Context, query dto.Query) (dto.EndpointCounts, *exceptions.Exception)
+	BrowserBreakdown(ctx *gin.Context, query dto.Query) (dto.Breakdowns, *exceptions.Exception)
+	OSBreakdown(ctx *gin.Context, query dto.Query) (dto.Breakdowns, *exceptions.Exception)
+	UniqueIPs(ctx *gin.Context, query dto.Query) (*dto.UniqueIPs, *exceptions.Exception)
+	TopReferrers(ctx *gin.Context, query dto.Query) (dto.Referrers, *exceptions.Exception)
+}
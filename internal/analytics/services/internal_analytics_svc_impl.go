@@ -0,0 +1,145 @@
+package services
+
+import (
+	"net/http"
+	"time"
+	"xanny-go/internal/analytics/dto"
+	"xanny-go/internal/analytics/repositories"
+	"xanny-go/pkg/exceptions"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultRange = 7 * 24 * time.Hour
+	defaultLimit = 10
+	maxLimit     = 100
+)
+
+type CompServicesImpl struct {
+	repo     repositories.CompRepositories
+	DB       *gorm.DB
+	validate *validator.Validate
+}
+
+func NewComponentServices(compRepositories repositories.CompRepositories, db *gorm.DB, validate *validator.Validate) CompServices {
+	return &CompServicesImpl{
+		repo:     compRepositories,
+		DB:       db,
+		validate: validate,
+	}
+}
+
+func (s *CompServicesImpl) RequestsOverTime(ctx *gin.Context, query dto.Query) (dto.TimeBuckets, *exceptions.Exception) {
+	filter, err := parseFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.RequestsOverTime(ctx, s.DB, *filter)
+}
+
+func (s *CompServicesImpl) TopEndpoints(ctx *gin.Context, query dto.Query) (dto.EndpointCounts, *exceptions.Exception) {
+	filter, err := parseFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.TopEndpoints(ctx, s.DB, *filter)
+}
+
+func (s *CompServicesImpl) BrowserBreakdown(ctx *gin.Context, query dto.Query) (dto.Breakdowns, *exceptions.Exception) {
+	filter, err := parseFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.BreakdownBy(ctx, s.DB, "browser", *filter)
+}
+
+func (s *CompServicesImpl) OSBreakdown(ctx *gin.Context, query dto.Query) (dto.Breakdowns, *exceptions.Exception) {
+	filter, err := parseFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.BreakdownBy(ctx, s.DB, "os", *filter)
+}
+
+func (s *CompServicesImpl) UniqueIPs(ctx *gin.Context, query dto.Query) (*dto.UniqueIPs, *exceptions.Exception) {
+	filter, err := parseFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.UniqueIPs(ctx, s.DB, *filter)
+}
+
+func (s *CompServicesImpl) TopReferrers(ctx *gin.Context, query dto.Query) (dto.Referrers, *exceptions.Exception) {
+	filter, err := parseFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.TopReferrers(ctx, s.DB, *filter)
+}
+
+// parseFilter turns the raw query string into a validated range. Dates accept
+// RFC 3339 or plain YYYY-MM-DD; a plain "to" date is inclusive of that day.
+func parseFilter(query dto.Query) (*dto.Filter, *exceptions.Exception) {
+	now := time.Now().UTC()
+	filter := dto.Filter{
+		From:     now.Add(-defaultRange),
+		To:       now,
+		Interval: "day",
+		Limit:    defaultLimit,
+	}
+
+	if query.From != "" {
+		from, _, err := parseDate(query.From)
+		if err != nil {
+			return nil, exceptions.NewException(http.StatusBadRequest, "from must be RFC 3339 or YYYY-MM-DD")
+		}
+		filter.From = from
+	}
+
+	if query.To != "" {
+		to, dateOnly, err := parseDate(query.To)
+		if err != nil {
+			return nil, exceptions.NewException(http.StatusBadRequest, "to must be RFC 3339 or YYYY-MM-DD")
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = to
+	}
+
+	if !filter.From.Before(filter.To) {
+		return nil, exceptions.NewException(http.StatusBadRequest, exceptions.ErrInvalidDate)
+	}
+
+	switch query.Interval {
+	case "":
+	case "hour", "day":
+		filter.Interval = query.Interval
+	default:
+		return nil, exceptions.NewException(http.StatusBadRequest, "interval must be hour or day")
+	}
+
+	if query.Limit > 0 {
+		filter.Limit = min(query.Limit, maxLimit)
+	}
+
+	return &filter, nil
+}
+
+func parseDate(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	return t, true, err
+}
@@ -4,7 +4,7 @@ import (
 	"net/http"
 	"xanny-go/internal/auth/dto"
 	"xanny-go/internal/auth/services"
-	"xanny-go/pkg/exceptions"
+	"xanny-go/pkg/helpers"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,9 +22,8 @@ func NewCompController(compServices services.CompServices) CompControllers {
 func (h *CompControllersImpl) Login(ctx *gin.Context) {
 	var data dto.Login
 
-	errRequest := ctx.ShouldBindJSON(&data)
-	if errRequest != nil {
-		ctx.JSON(http.StatusBadRequest, exceptions.NewException(http.StatusBadRequest, errRequest.Error()))
+	if bindErr := helpers.BindJSON(ctx, &data); bindErr != nil {
+		ctx.JSON(bindErr.Status, bindErr)
 		return
 	}
 
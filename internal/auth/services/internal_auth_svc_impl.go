@@ -6,6 +6,7 @@ import (
 	"xanny-go/internal/auth/dto"
 	"xanny-go/pkg/config"
 	"xanny-go/pkg/exceptions"
+	"xanny-go/pkg/helpers"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
@@ -27,7 +28,7 @@ func NewComponentServices(validate *validator.Validate) CompServices {
 func (s *CompServicesImpl) Login(ctx *gin.Context, data dto.Login) (*string, *exceptions.Exception) {
 	validateErr := s.validate.Struct(data)
 	if validateErr != nil {
-		return nil, exceptions.NewValidationException(validateErr)
+		return nil, helpers.ValidationException(data, validateErr)
 	}
 
 	ADMIN_USERNAME := config.GetAdminUsername()
@@ -6,11 +6,16 @@
 package injectors
 
 import (
+	analyticsControllers "xanny-go/internal/analytics/controllers"
+	analyticsRepositories "xanny-go/internal/analytics/repositories"
+	analyticsServices "xanny-go/internal/analytics/services"
 	authControllers "xanny-go/internal/auth/controllers"
 	authServices "xanny-go/internal/auth/services"
+	"xanny-go/pkg/cache"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/wire"
+	"gorm.io/gorm"
 )
 
 var authFeatureSet = wire.NewSet(
@@ -18,7 +23,28 @@ var authFeatureSet = wire.NewSet(
 	authControllers.NewCompController,
 )
 
+var analyticsFeatureSet = wire.NewSet(
+	analyticsRepositories.NewComponentRepository,
+	analyticsServices.NewComponentServices,
+	analyticsControllers.NewCompController,
+)
+
+var cacheFeatureSet = wire.NewSet(
+	cache.NewCacheService,
+	cache.NewCacheController,
+)
+
 func InitializeAuthController(validate *validator.Validate) authControllers.CompControllers {
 	wire.Build(authFeatureSet)
 	return nil
 }
+
+func InitializeAnalyticsController(db *gorm.DB, validate *validator.Validate) analyticsControllers.CompControllers {
+	wire.Build(analyticsFeatureSet)
+	return nil
+}
+
+func InitializeCacheController(appCache cache.Cache) *cache.CacheController {
+	wire.Build(cacheFeatureSet)
+	return nil
+}
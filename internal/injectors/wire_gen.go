@@ -9,8 +9,13 @@ package injectors
 import (
 	"github.com/go-playground/validator/v10"
 	"github.com/google/wire"
+	"gorm.io/gorm"
+	controllers2 "xanny-go/internal/analytics/controllers"
+	"xanny-go/internal/analytics/repositories"
+	services2 "xanny-go/internal/analytics/services"
 	"xanny-go/internal/auth/controllers"
 	"xanny-go/internal/auth/services"
+	"xanny-go/pkg/cache"
 )
 
 // Injectors from injector.go:
@@ -21,6 +26,23 @@ func InitializeAuthController(validate *validator.Validate) controllers.CompCont
 	return compControllers
 }
 
+func InitializeAnalyticsController(db *gorm.DB, validate *validator.Validate) controllers2.CompControllers {
+	compRepositories := repositories.NewComponentRepository()
+	compServices := services2.NewComponentServices(compRepositories, db, validate)
+	compControllers := controllers2.NewCompController(compServices)
+	return compControllers
+}
+
+func InitializeCacheController(appCache cache.Cache) *cache.CacheController {
+	cacheService := cache.NewCacheService(appCache)
+	cacheController := cache.NewCacheController(cacheService)
+	return cacheController
+}
+
 // injector.go:
 
 var authFeatureSet = wire.NewSet(services.NewComponentServices, controllers.NewCompController)
+
+var analyticsFeatureSet = wire.NewSet(repositories.NewComponentRepository, services2.NewComponentServices, controllers2.NewCompController)
+
+var cacheFeatureSet = wire.NewSet(cache.NewCacheService, cache.NewCacheController)
@@ -0,0 +1,21 @@
+package routers
+
+import (
+	"xanny-go/internal/analytics/controllers"
+	"xanny-go/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AnalyticsRoutes(r *gin.RouterGroup, analyticsController controllers.CompControllers) {
+	analyticsGroup := r.Group("/analytics")
+	analyticsGroup.Use(middleware.InternalMiddleware())
+	{
+		analyticsGroup.GET("/requests", analyticsController.RequestsOverTime)
+		analyticsGroup.GET("/endpoints", analyticsController.TopEndpoints)
+		analyticsGroup.GET("/browsers", analyticsController.BrowserBreakdown)
+		analyticsGroup.GET("/os", analyticsController.OSBreakdown)
+		analyticsGroup.GET("/unique-ips", analyticsController.UniqueIPs)
+		analyticsGroup.GET("/referrers", analyticsController.TopReferrers)
+	}
+}
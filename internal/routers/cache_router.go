@@ -0,0 +1,22 @@
+package routers
+
+import (
+	"xanny-go/pkg/cache"
+	"xanny-go/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func CacheRoutes(r *gin.RouterGroup, cacheController *cache.CacheController) {
+	cacheGroup := r.Group("/cache")
+	cacheGroup.Use(middleware.InternalMiddleware())
+	{
+		cacheGroup.GET("/stats", cacheController.GetCacheStats)
+		cacheGroup.GET("/metrics", cacheController.GetCacheMetrics)
+		cacheGroup.POST("/flush", cacheController.FlushCache)
+		cacheGroup.POST("/invalidate", cacheController.InvalidatePattern)
+		cacheGroup.POST("/set", cacheController.SetCacheValue)
+		cacheGroup.GET("/:key", cacheController.GetCacheValue)
+		cacheGroup.DELETE("/:key", cacheController.DeleteCacheValue)
+	}
+}
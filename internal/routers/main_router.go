@@ -2,14 +2,23 @@ package routers
 
 import (
 	"xanny-go/internal/injectors"
+	"xanny-go/pkg/cache"
+	"xanny-go/pkg/middleware"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
 )
 
-func InternalRouters(r *gin.RouterGroup, db *gorm.DB, validate *validator.Validate) {
+func InternalRouters(r *gin.RouterGroup, db *gorm.DB, validate *validator.Validate, appCache cache.Cache, clientTracker *middleware.ClientTracker) {
 	internalController := injectors.InitializeAuthController(validate)
 
+	analyticsController := injectors.InitializeAnalyticsController(db, validate)
+
+	cacheController := injectors.InitializeCacheController(appCache)
+
 	AuthRoutes(r, internalController)
+	AnalyticsRoutes(r, analyticsController)
+	CacheRoutes(r, cacheController)
+	TrackerRoutes(r, clientTracker)
 }
@@ -0,0 +1,15 @@
+package routers
+
+import (
+	"xanny-go/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TrackerRoutes(r *gin.RouterGroup, clientTracker *middleware.ClientTracker) {
+	trackerGroup := r.Group("/tracker")
+	trackerGroup.Use(middleware.InternalMiddleware())
+	{
+		trackerGroup.GET("/stats", clientTracker.StatsHandler())
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ClientDailyRollup struct {
+	gorm.Model
+
+	Day          time.Time `gorm:"not null;index"`
+	Endpoint     string    `gorm:"not null;index"`
+	Browser      string
+	OS           string
+	Device       string
+	Requests     int64   `gorm:"not null"`
+	Errors       int64   `gorm:"not null"`
+	UniqueIPs    int64   `gorm:"not null"`
+	AvgLatencyMs float64 `gorm:"not null"`
+}
@@ -4,11 +4,14 @@ import "gorm.io/gorm"
 
 type Clients struct {
 	gorm.Model
-	IP        string
-	Browser   string
-	Version   string
-	OS        string
-	Device    string
-	Origin    string
-	API       string
+	IP         string
+	Browser    string
+	Version    string
+	OS         string
+	Device     string
+	Origin     string
+	API        string
+	StatusCode int
+	LatencyMs  float64
+	UserUUID   string `gorm:"index"`
 }
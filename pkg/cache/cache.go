@@ -2,11 +2,19 @@ package cache
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
+// ErrKeyNotFound is returned, possibly wrapped, by Get when a key is missing
+// or has expired
+var ErrKeyNotFound = errors.New("key not found")
+
 // Cache interface defines the contract for all cache implementations
 type Cache interface {
 	// Get retrieves a value from cache by key
@@ -24,26 +32,63 @@ type Cache interface {
 	// Flush removes all keys from cache
 	Flush(ctx context.Context) error
 
-	// InvalidatePattern removes all keys matching a pattern
+	// InvalidatePattern removes all keys matching a glob pattern, see
+	// MatchPattern for the syntax
 	InvalidatePattern(ctx context.Context, pattern string) error
 
 	// Close closes the cache connection
 	Close() error
 }
 
+// PatternDeleter is implemented by caches that can report how many keys a
+// pattern invalidation removed
+type PatternDeleter interface {
+	// DeletePattern removes all keys matching a glob pattern and returns the
+	// number deleted
+	DeletePattern(ctx context.Context, pattern string) (int64, error)
+}
+
+// ExistingSetter is implemented by caches that can overwrite a key only while
+// it exists, so a write racing a Delete cannot bring the key back
+type ExistingSetter interface {
+	// SetXX stores a value only if the key exists and reports whether it was
+	// stored
+	SetXX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error)
+}
+
+// AsExistingSetter returns cache as an ExistingSetter if it supports SetXX.
+// An InstrumentedCache always has the method, so it is judged by the cache
+// it wraps
+func AsExistingSetter(cache Cache) (ExistingSetter, bool) {
+	if _, ok := unwrapCache(cache).(ExistingSetter); !ok {
+		return nil, false
+	}
+	setter, ok := cache.(ExistingSetter)
+	return setter, ok
+}
+
 // CacheOptions holds configuration options for cache implementations
 type CacheOptions struct {
 	DefaultTTL time.Duration
-	MaxSize    int
-	Prefix     string
+	// MaxSize caps the number of entries in memory caches; zero means unbounded
+	MaxSize int
+	// MaxBytes caps the combined key and value size in memory caches; zero
+	// means unbounded
+	MaxBytes       int64
+	EvictionPolicy EvictionPolicy
+	Prefix         string
+	// NegativeTTL is how long CacheHelper remembers that a value does not
+	// exist; zero disables negative caching
+	NegativeTTL time.Duration
 }
 
 // DefaultCacheOptions returns default cache options
 func DefaultCacheOptions() *CacheOptions {
 	return &CacheOptions{
-		DefaultTTL: 5 * time.Minute,
-		MaxSize:    1000,
-		Prefix:     "cache:",
+		DefaultTTL:     5 * time.Minute,
+		MaxSize:        1000,
+		EvictionPolicy: EvictionLRU,
+		Prefix:         "cache:",
 	}
 }
 
@@ -65,8 +110,12 @@ func NewCacheManager(opts *CacheOptions) *CacheManager {
 	}
 }
 
-// RegisterCache registers a cache instance with a name
+// RegisterCache registers a cache instance with a name, wrapping it so its
+// operations are recorded in metrics labeled with that name
 func (cm *CacheManager) RegisterCache(name string, cache Cache) {
+	if _, ok := cache.(*InstrumentedCache); !ok {
+		cache = Instrument(name, cache)
+	}
 	cm.caches[name] = cache
 }
 
@@ -79,6 +128,23 @@ func (cm *CacheManager) GetCache(name string) (Cache, error) {
 	return cache, nil
 }
 
+// Metrics returns the metrics of every registered cache, sorted by name
+func (cm *CacheManager) Metrics() []*CacheMetrics {
+	names := make([]string, 0, len(cm.caches))
+	for name := range cm.caches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metrics := make([]*CacheMetrics, 0, len(names))
+	for _, name := range names {
+		if instrumented, ok := cm.caches[name].(*InstrumentedCache); ok {
+			metrics = append(metrics, instrumented.Metrics())
+		}
+	}
+	return metrics
+}
+
 // CloseAll closes all registered caches
 func (cm *CacheManager) CloseAll() error {
 	for name, cache := range cm.caches {
@@ -96,8 +162,14 @@ func (cm *CacheManager) CacheKey(key string) string {
 
 // CacheHelper provides convenient methods for common cache operations
 type CacheHelper struct {
-	cache Cache
-	opts  *CacheOptions
+	cache      Cache
+	opts       *CacheOptions
+	refresh    RefreshOptions
+	locker     *Locker
+	serializer *Serializer
+
+	group      singleflight.Group
+	refreshing sync.Map
 }
 
 // NewCacheHelper creates a new cache helper
@@ -107,86 +179,94 @@ func NewCacheHelper(cache Cache, opts *CacheOptions) *CacheHelper {
 	}
 
 	return &CacheHelper{
-		cache: cache,
-		opts:  opts,
+		cache:      cache,
+		opts:       opts,
+		serializer: DefaultSerializer(),
 	}
 }
 
-// GetJSON retrieves and unmarshals a JSON value from cache
+// WithSerializer sets how the JSON methods encode values and returns the
+// helper. Values already stored with another codec remain readable
+func (ch *CacheHelper) WithSerializer(serializer *Serializer) *CacheHelper {
+	ch.serializer = serializer
+	return ch
+}
+
+// GetJSON retrieves and decodes a value from cache. The JSON methods use the
+// helper's Serializer, which writes plain JSON unless configured otherwise
 func (ch *CacheHelper) GetJSON(ctx context.Context, key string, dest interface{}) error {
 	data, err := ch.cache.Get(ctx, key)
 	if err != nil {
 		return err
 	}
+	if isNegativeEntry(data) {
+		return ErrNotFound
+	}
 
-	return json.Unmarshal(data, dest)
+	return ch.serializer.Unmarshal(decodeRefreshEntry(data).value, dest)
 }
 
-// SetJSON marshals and stores a JSON value in cache
-func (ch *CacheHelper) SetJSON(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
-	data, err := json.Marshal(value)
-	if err != nil {
-		return err
-	}
-
+// Set stores a value in cache, recording it under tags when given. Tags need
+// a cache implementing TaggedCache
+func (ch *CacheHelper) Set(ctx context.Context, key string, value []byte, expiration time.Duration, tags ...string) error {
 	if expiration == 0 {
 		expiration = ch.opts.DefaultTTL
 	}
 
-	return ch.cache.Set(ctx, key, data, expiration)
+	return setTagged(ctx, ch.cache, key, value, expiration, tags)
 }
 
-// GetOrSet retrieves a value from cache, or sets it if not found
-func (ch *CacheHelper) GetOrSet(ctx context.Context, key string, fn func() ([]byte, time.Duration, error)) ([]byte, error) {
-	// Try to get from cache first
-	if data, err := ch.cache.Get(ctx, key); err == nil {
-		return data, nil
-	}
-
-	// If not found, execute the function to get the value
-	data, expiration, err := fn()
+// SetJSON encodes and stores a value in cache, recording it under tags when
+// given
+func (ch *CacheHelper) SetJSON(ctx context.Context, key string, value interface{}, expiration time.Duration, tags ...string) error {
+	data, err := ch.serializer.Marshal(value)
 	if err != nil {
-		return nil, err
-	}
-
-	// Store in cache
-	if err := ch.cache.Set(ctx, key, data, expiration); err != nil {
-		// Log error but don't fail the operation
-		// You might want to add proper logging here
+		return err
 	}
 
-	return data, nil
+	return ch.Set(ctx, key, data, expiration, tags...)
 }
 
-// GetOrSetJSON retrieves a JSON value from cache, or sets it if not found
-func (ch *CacheHelper) GetOrSetJSON(ctx context.Context, key string, dest interface{}, fn func() (interface{}, time.Duration, error)) error {
-	// Try to get from cache first
-	if err := ch.GetJSON(ctx, key, dest); err == nil {
-		return nil
-	}
-
-	// If not found, execute the function to get the value
-	value, expiration, err := fn()
-	if err != nil {
-		return err
-	}
+// GetOrSet retrieves a value from cache, or sets it under tags if not found.
+// Concurrent misses share one call to fn; see RefreshOptions for
+// stale-while-revalidate, early expiration and cross-replica locking. When fn
+// reports a missing value (see IsNotFound) the absence is cached for
+// NegativeTTL and ErrNotFound is returned until it expires
+func (ch *CacheHelper) GetOrSet(ctx context.Context, key string, fn func() ([]byte, time.Duration, error), tags ...string) ([]byte, error) {
+	return ch.getOrLoad(ctx, key, fn, tags)
+}
 
-	// Store in cache
-	if err := ch.SetJSON(ctx, key, value, expiration); err != nil {
-		// Log error but don't fail the operation
-		// You might want to add proper logging here
-	}
+// GetOrSetJSON retrieves a JSON value from cache, or sets it under tags if
+// not found. It behaves like GetOrSet
+func (ch *CacheHelper) GetOrSetJSON(ctx context.Context, key string, dest interface{}, fn func() (interface{}, time.Duration, error), tags ...string) error {
+	data, err := ch.getOrLoad(ctx, key, func() ([]byte, time.Duration, error) {
+		value, expiration, err := fn()
+		if err != nil {
+			return nil, 0, err
+		}
 
-	// Set the destination
-	data, err := json.Marshal(value)
+		data, err := ch.serializer.Marshal(value)
+		return data, expiration, err
+	}, tags)
 	if err != nil {
 		return err
 	}
 
-	return json.Unmarshal(data, dest)
+	return ch.serializer.Unmarshal(data, dest)
 }
 
 // InvalidatePattern invalidates all keys matching a pattern (if supported)
 func (ch *CacheHelper) InvalidatePattern(ctx context.Context, pattern string) error {
 	return ch.cache.InvalidatePattern(ctx, pattern)
 }
+
+// InvalidateTags removes every entry carrying any of the tags
+func (ch *CacheHelper) InvalidateTags(ctx context.Context, tags ...string) error {
+	tagged, ok := AsTaggedCache(ch.cache)
+	if !ok {
+		return ErrTagsNotSupported
+	}
+
+	_, err := tagged.InvalidateTags(ctx, tags...)
+	return err
+}
@@ -0,0 +1,291 @@
+// Package cachetest checks that a cache.Cache implementation behaves the way
+// the rest of the code relies on. It follows testing/fstest: TestCache returns
+// an error instead of taking a *testing.T, so the same checks can run from a
+// test, a health probe or a one-off command against a real backend.
+package cachetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"xanny-go/pkg/cache"
+)
+
+// ExpiryWait is how long TestCache waits for a short-lived entry to expire
+var ExpiryWait = 1500 * time.Millisecond
+
+// TestCache runs the conformance checks against c, including the tag and
+// SetXX checks when cache.AsTaggedCache and cache.AsExistingSetter report
+// support. The cache must be empty and not shared with anything else while
+// the checks run, since Flush is part of the suite. All failures are reported
+// together.
+func TestCache(ctx context.Context, c cache.Cache) error {
+	t := &checker{ctx: ctx, cache: c}
+
+	t.run("missing keys", checkMissing)
+	t.run("set and get", checkSetGet)
+	t.run("delete", checkDelete)
+	t.run("expiration", checkExpiration)
+	t.run("glob patterns", checkPatterns)
+	t.run("flush", checkFlush)
+
+	if _, ok := cache.AsTaggedCache(c); ok {
+		t.run("tags", checkTags)
+	}
+	if _, ok := cache.AsExistingSetter(c); ok {
+		t.run("set if exists", checkSetXX)
+	}
+
+	return errors.Join(t.failures...)
+}
+
+type checker struct {
+	ctx      context.Context
+	cache    cache.Cache
+	name     string
+	failures []error
+}
+
+func (t *checker) run(name string, check func(t *checker)) {
+	t.name = name
+	check(t)
+	t.cache.Flush(t.ctx)
+}
+
+func (t *checker) errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Errorf("%s: %s", t.name, fmt.Sprintf(format, args...)))
+}
+
+func (t *checker) set(key string, value []byte, expiration time.Duration) bool {
+	if err := t.cache.Set(t.ctx, key, value, expiration); err != nil {
+		t.errorf("Set(%q): %v", key, err)
+		return false
+	}
+	return true
+}
+
+func (t *checker) expectValue(key string, want []byte) {
+	got, err := t.cache.Get(t.ctx, key)
+	if err != nil {
+		t.errorf("Get(%q): %v", key, err)
+		return
+	}
+	if !bytes.Equal(got, want) {
+		t.errorf("Get(%q) = %q, want %q", key, got, want)
+	}
+}
+
+func (t *checker) expectExists(key string, want bool) {
+	exists, err := t.cache.Exists(t.ctx, key)
+	if err != nil {
+		t.errorf("Exists(%q): %v", key, err)
+		return
+	}
+	if exists != want {
+		t.errorf("Exists(%q) = %v, want %v", key, exists, want)
+	}
+}
+
+func (t *checker) expectMissing(key string) {
+	if value, err := t.cache.Get(t.ctx, key); err == nil {
+		t.errorf("Get(%q) = %q, want an error", key, value)
+	} else if !errors.Is(err, cache.ErrKeyNotFound) {
+		t.errorf("Get(%q) error %q does not wrap cache.ErrKeyNotFound", key, err)
+	}
+	t.expectExists(key, false)
+}
+
+func checkMissing(t *checker) {
+	t.expectMissing("cachetest:missing")
+
+	if err := t.cache.Delete(t.ctx, "cachetest:missing"); err != nil {
+		t.errorf("Delete of a missing key: %v", err)
+	}
+}
+
+func checkSetGet(t *checker) {
+	binary := []byte{0, 1, 2, 0xff, '\n', 0}
+	if t.set("cachetest:binary", binary, time.Minute) {
+		t.expectValue("cachetest:binary", binary)
+		t.expectExists("cachetest:binary", true)
+	}
+
+	if t.set("cachetest:overwrite", []byte("first"), time.Minute) && t.set("cachetest:overwrite", []byte("second"), time.Minute) {
+		t.expectValue("cachetest:overwrite", []byte("second"))
+	}
+
+	if t.set("cachetest:empty", []byte{}, time.Minute) {
+		t.expectValue("cachetest:empty", []byte{})
+	}
+}
+
+func checkDelete(t *checker) {
+	if !t.set("cachetest:delete", []byte("value"), time.Minute) {
+		return
+	}
+
+	if err := t.cache.Delete(t.ctx, "cachetest:delete"); err != nil {
+		t.errorf("Delete: %v", err)
+		return
+	}
+	t.expectMissing("cachetest:delete")
+}
+
+func checkExpiration(t *checker) {
+	if !t.set("cachetest:short", []byte("value"), time.Second) || !t.set("cachetest:long", []byte("value"), time.Minute) {
+		return
+	}
+
+	select {
+	case <-time.After(ExpiryWait):
+	case <-t.ctx.Done():
+		t.errorf("%v", t.ctx.Err())
+		return
+	}
+
+	t.expectMissing("cachetest:short")
+	t.expectValue("cachetest:long", []byte("value"))
+}
+
+var patternKeys = []string{
+	"user:1",
+	"user:2",
+	"user:10",
+	"users",
+	"post:1",
+	"host:1",
+	"a*b",
+	"axb",
+}
+
+var patternCases = []struct {
+	pattern string
+	deletes []string
+}{
+	{"user:1", []string{"user:1"}},
+	{"user:?", []string{"user:1", "user:2"}},
+	{"user:*", []string{"user:1", "user:2", "user:10"}},
+	{"user*", []string{"user:1", "user:2", "user:10", "users"}},
+	{"[ph]ost:1", []string{"post:1", "host:1"}},
+	{"[^p]ost:*", []string{"host:1"}},
+	{"[a-o]ost:1", []string{"host:1"}},
+	{`a\*b`, []string{"a*b"}},
+	{"a*b", []string{"a*b", "axb"}},
+	{"*", patternKeys},
+	{"nothing:*", nil},
+}
+
+func checkPatterns(t *checker) {
+	for _, tc := range patternCases {
+		t.cache.Flush(t.ctx)
+		for _, key := range patternKeys {
+			if !t.set(key, []byte(key), time.Minute) {
+				return
+			}
+		}
+
+		if err := t.cache.InvalidatePattern(t.ctx, tc.pattern); err != nil {
+			t.errorf("InvalidatePattern(%q): %v", tc.pattern, err)
+			continue
+		}
+
+		var deleted []string
+		for _, key := range patternKeys {
+			exists, err := t.cache.Exists(t.ctx, key)
+			if err != nil {
+				t.errorf("Exists(%q): %v", key, err)
+				return
+			}
+			if !exists {
+				deleted = append(deleted, key)
+			}
+		}
+
+		want := append([]string(nil), tc.deletes...)
+		sort.Strings(deleted)
+		sort.Strings(want)
+		if fmt.Sprint(deleted) != fmt.Sprint(want) {
+			t.errorf("InvalidatePattern(%q) deleted %v, want %v", tc.pattern, deleted, want)
+		}
+	}
+}
+
+func checkFlush(t *checker) {
+	for _, key := range []string{"cachetest:flush:1", "cachetest:flush:2"} {
+		if !t.set(key, []byte("value"), time.Minute) {
+			return
+		}
+	}
+
+	if err := t.cache.Flush(t.ctx); err != nil {
+		t.errorf("Flush: %v", err)
+		return
+	}
+
+	t.expectMissing("cachetest:flush:1")
+	t.expectMissing("cachetest:flush:2")
+}
+
+func checkTags(t *checker) {
+	tagged, _ := cache.AsTaggedCache(t.cache)
+
+	entries := map[string][]string{
+		"cachetest:tag:1": {"red"},
+		"cachetest:tag:2": {"red", "blue"},
+		"cachetest:tag:3": {"blue"},
+		"cachetest:tag:4": nil,
+	}
+	for key, tags := range entries {
+		if err := tagged.SetWithTags(t.ctx, key, []byte(key), time.Minute, tags...); err != nil {
+			t.errorf("SetWithTags(%q): %v", key, err)
+			return
+		}
+	}
+
+	deleted, err := tagged.InvalidateTags(t.ctx, "red")
+	if err != nil {
+		t.errorf("InvalidateTags(red): %v", err)
+		return
+	}
+	if deleted != 2 {
+		t.errorf("InvalidateTags(red) = %d, want 2", deleted)
+	}
+
+	t.expectMissing("cachetest:tag:1")
+	t.expectMissing("cachetest:tag:2")
+	t.expectValue("cachetest:tag:3", []byte("cachetest:tag:3"))
+	t.expectValue("cachetest:tag:4", []byte("cachetest:tag:4"))
+
+	if deleted, err := tagged.InvalidateTags(t.ctx, "red"); err != nil || deleted != 0 {
+		t.errorf("second InvalidateTags(red) = %d, %v, want 0, nil", deleted, err)
+	}
+}
+
+func checkSetXX(t *checker) {
+	setter, _ := cache.AsExistingSetter(t.cache)
+
+	if stored, err := setter.SetXX(t.ctx, "cachetest:xx", []byte("value"), time.Minute); err != nil || stored {
+		t.errorf("SetXX of a missing key = %v, %v, want false, nil", stored, err)
+	}
+	t.expectMissing("cachetest:xx")
+
+	if !t.set("cachetest:xx", []byte("first"), time.Minute) {
+		return
+	}
+	if stored, err := setter.SetXX(t.ctx, "cachetest:xx", []byte("second"), time.Minute); err != nil || !stored {
+		t.errorf("SetXX of an existing key = %v, %v, want true, nil", stored, err)
+	}
+	t.expectValue("cachetest:xx", []byte("second"))
+
+	if err := t.cache.Delete(t.ctx, "cachetest:xx"); err != nil {
+		t.errorf("Delete: %v", err)
+		return
+	}
+	if stored, err := setter.SetXX(t.ctx, "cachetest:xx", []byte("third"), time.Minute); err != nil || stored {
+		t.errorf("SetXX after Delete = %v, %v, want false, nil", stored, err)
+	}
+	t.expectMissing("cachetest:xx")
+}
@@ -0,0 +1,337 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// valueHeaderMagic starts every value written with a non-JSON codec or
+// compression. It can never begin JSON text or a MessagePack value
+const valueHeaderMagic = 0xC1
+
+// valueHeaderSize is the magic byte, the codec ID and the compressor ID
+const valueHeaderSize = 3
+
+// DefaultCompressThreshold is the smallest encoded value, in bytes, that is
+// compressed when a Serializer has a compressor but no threshold
+const DefaultCompressThreshold = 1024
+
+// Codec serializes values for storage in a cache
+type Codec interface {
+	// Name identifies the codec in configuration
+	Name() string
+
+	// ID is written in the header of every stored value so it can be read
+	// back whichever codec is configured at the time. IDs must be unique
+	// and non-zero
+	ID() byte
+
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// Compressor compresses encoded values before they are stored
+type Compressor interface {
+	// Name identifies the compressor in configuration
+	Name() string
+
+	// ID is written in the header of every stored value. IDs must be unique
+	// and non-zero
+	ID() byte
+
+	Compress(src []byte) ([]byte, error)
+	Decompress(src []byte) ([]byte, error)
+}
+
+// Built-in codecs and compressors
+var (
+	JSONCodec    Codec = jsonCodec{}
+	MsgpackCodec Codec = msgpackCodec{}
+	GobCodec     Codec = gobCodec{}
+
+	ZstdCompressor   Compressor = zstdCompressor{}
+	SnappyCompressor Compressor = snappyCompressor{}
+)
+
+var (
+	codecsMu    sync.RWMutex
+	codecs      = map[byte]Codec{}
+	compressors = map[byte]Compressor{}
+)
+
+func init() {
+	for _, codec := range []Codec{JSONCodec, MsgpackCodec, GobCodec} {
+		RegisterCodec(codec)
+	}
+	for _, compressor := range []Compressor{ZstdCompressor, SnappyCompressor} {
+		RegisterCompressor(compressor)
+	}
+}
+
+// RegisterCodec makes a codec available for decoding stored values and for
+// lookup by name. Registering an ID twice replaces the earlier codec
+func RegisterCodec(codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[codec.ID()] = codec
+}
+
+// RegisterCompressor makes a compressor available for decoding stored values
+// and for lookup by name
+func RegisterCompressor(compressor Compressor) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	compressors[compressor.ID()] = compressor
+}
+
+// CodecByName returns the registered codec with the given name
+func CodecByName(name string) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	for _, codec := range codecs {
+		if codec.Name() == name {
+			return codec, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown cache codec: %s", name)
+}
+
+// CompressorByName returns the registered compressor with the given name.
+// An empty name or "none" means no compression and returns nil
+func CompressorByName(name string) (Compressor, error) {
+	if name == "" || name == "none" {
+		return nil, nil
+	}
+
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	for _, compressor := range compressors {
+		if compressor.Name() == name {
+			return compressor, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown cache compressor: %s", name)
+}
+
+// Serializer turns values into the bytes stored in a cache and back.
+// Values written with JSON and no compression are stored as plain JSON, so
+// they stay readable by older code. Anything else gets a three-byte header
+// naming the codec and compressor, which lets Unmarshal read values written
+// under any registered codec: switching codecs needs no flush.
+type Serializer struct {
+	Codec Codec
+
+	// Compressor, if set, compresses encoded values of at least
+	// CompressThreshold bytes
+	Compressor        Compressor
+	CompressThreshold int
+}
+
+// DefaultSerializer returns a serializer writing plain JSON
+func DefaultSerializer() *Serializer {
+	return &Serializer{Codec: JSONCodec}
+}
+
+// NewSerializer creates a serializer from codec and compressor names, as
+// used in configuration
+func NewSerializer(codec, compressor string, threshold int) (*Serializer, error) {
+	if codec == "" {
+		codec = JSONCodec.Name()
+	}
+
+	c, err := CodecByName(codec)
+	if err != nil {
+		return nil, err
+	}
+
+	comp, err := CompressorByName(compressor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Serializer{Codec: c, Compressor: comp, CompressThreshold: threshold}, nil
+}
+
+// Marshal encodes v with the serializer's codec, compressing it when large
+// enough
+func (s *Serializer) Marshal(v interface{}) ([]byte, error) {
+	codec := s.Codec
+	if codec == nil {
+		codec = JSONCodec
+	}
+
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := s.CompressThreshold
+	if threshold <= 0 {
+		threshold = DefaultCompressThreshold
+	}
+
+	var compressorID byte
+	if s.Compressor != nil && len(data) >= threshold {
+		if data, err = s.Compressor.Compress(data); err != nil {
+			return nil, err
+		}
+		compressorID = s.Compressor.ID()
+	}
+
+	if codec.ID() == JSONCodec.ID() && compressorID == 0 {
+		return data, nil
+	}
+
+	framed := make([]byte, valueHeaderSize+len(data))
+	framed[0] = valueHeaderMagic
+	framed[1] = codec.ID()
+	framed[2] = compressorID
+	copy(framed[valueHeaderSize:], data)
+	return framed, nil
+}
+
+// Unmarshal decodes data written by any serializer into v. Values without a
+// header are read as JSON
+func (s *Serializer) Unmarshal(data []byte, v interface{}) error {
+	if len(data) < valueHeaderSize || data[0] != valueHeaderMagic {
+		return json.Unmarshal(data, v)
+	}
+
+	codecsMu.RLock()
+	codec, ok := codecs[data[1]]
+	compressor := compressors[data[2]]
+	codecsMu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("unknown cache codec id: %d", data[1])
+	}
+
+	payload := data[valueHeaderSize:]
+	if data[2] != 0 {
+		if compressor == nil {
+			return fmt.Errorf("unknown cache compressor id: %d", data[2])
+		}
+
+		var err error
+		if payload, err = compressor.Decompress(payload); err != nil {
+			return err
+		}
+	}
+
+	return codec.Unmarshal(payload, v)
+}
+
+// jsonCodec encodes values with encoding/json
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+func (jsonCodec) ID() byte     { return 1 }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// msgpackCodec encodes values with MessagePack. Struct fields are named by
+// their json tags so switching from JSON keeps the same field names
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string { return "msgpack" }
+func (msgpackCodec) ID() byte     { return 2 }
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	dec.SetMapDecoder(func(d *msgpack.Decoder) (interface{}, error) {
+		return d.DecodeUntypedMap()
+	})
+	return dec.Decode(v)
+}
+
+// gobCodec encodes values with encoding/gob. Concrete types stored behind
+// interfaces must be registered with gob.Register
+type gobCodec struct{}
+
+func (gobCodec) Name() string { return "gob" }
+func (gobCodec) ID() byte     { return 3 }
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// zstdEncoder and zstdDecoder are shared; their EncodeAll and DecodeAll
+// methods are safe for concurrent use
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+		return zstd.NewWriter(nil)
+	})
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil)
+	})
+)
+
+// zstdCompressor compresses with Zstandard
+type zstdCompressor struct{}
+
+func (zstdCompressor) Name() string { return "zstd" }
+func (zstdCompressor) ID() byte     { return 1 }
+
+func (zstdCompressor) Compress(src []byte) ([]byte, error) {
+	enc, err := zstdEncoder()
+	if err != nil {
+		return nil, err
+	}
+	return enc.EncodeAll(src, nil), nil
+}
+
+func (zstdCompressor) Decompress(src []byte) ([]byte, error) {
+	dec, err := zstdDecoder()
+	if err != nil {
+		return nil, err
+	}
+	return dec.DecodeAll(src, nil)
+}
+
+// snappyCompressor compresses with Snappy, trading ratio for speed
+type snappyCompressor struct{}
+
+func (snappyCompressor) Name() string { return "snappy" }
+func (snappyCompressor) ID() byte     { return 2 }
+
+func (snappyCompressor) Compress(src []byte) ([]byte, error) {
+	return snappy.Encode(nil, src), nil
+}
+
+func (snappyCompressor) Decompress(src []byte) ([]byte, error) {
+	return snappy.Decode(nil, src)
+}
@@ -2,135 +2,26 @@ package cache
 
 import (
 	"net/http"
-	"strconv"
 	"time"
+	"xanny-go/pkg/helpers"
 
 	"github.com/gin-gonic/gin"
 )
 
-// CacheController handles cache-related HTTP requests
+// CacheController handles the cache admin HTTP requests
 type CacheController struct {
-	cacheService   *CacheService
-	userService    *UserService
-	productService *ProductService
-	statsService   *CacheStatsService
+	cacheService *CacheService
+	statsService *CacheStatsService
 }
 
 // NewCacheController creates a new cache controller
 func NewCacheController(cacheService *CacheService) *CacheController {
 	return &CacheController{
-		cacheService:   cacheService,
-		userService:    NewUserService(cacheService),
-		productService: NewProductService(cacheService),
-		statsService:   NewCacheStatsService(cacheService),
+		cacheService: cacheService,
+		statsService: NewCacheStatsService(cacheService),
 	}
 }
 
-// GetUser handles GET /users/:id
-func (cc *CacheController) GetUser(c *gin.Context) {
-	userID := c.Param("id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
-		return
-	}
-
-	user, err := cc.userService.GetUser(c.Request.Context(), userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"data":   user,
-		"cached": true, // This would be determined by cache hit/miss
-	})
-}
-
-// GetUsers handles GET /users
-func (cc *CacheController) GetUsers(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	users, err := cc.userService.GetUsers(c.Request.Context(), page, limit)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"data": users,
-		"pagination": gin.H{
-			"page":  page,
-			"limit": limit,
-		},
-	})
-}
-
-// UpdateUser handles PUT /users/:id
-func (cc *CacheController) UpdateUser(c *gin.Context) {
-	userID := c.Param("id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
-		return
-	}
-
-	var updates map[string]interface{}
-	if err := c.ShouldBindJSON(&updates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	err := cc.userService.UpdateUser(c.Request.Context(), userID, updates)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message":           "User updated successfully",
-		"cache_invalidated": true,
-	})
-}
-
-// GetProduct handles GET /products/:id
-func (cc *CacheController) GetProduct(c *gin.Context) {
-	productID := c.Param("id")
-	if productID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "product ID is required"})
-		return
-	}
-
-	product, err := cc.productService.GetProduct(c.Request.Context(), productID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"data": product,
-	})
-}
-
-// GetProductPrice handles GET /products/:id/price
-func (cc *CacheController) GetProductPrice(c *gin.Context) {
-	productID := c.Param("id")
-	if productID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "product ID is required"})
-		return
-	}
-
-	price, err := cc.productService.GetProductPrice(c.Request.Context(), productID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"product_id": productID,
-		"price":      price,
-	})
-}
-
 // GetCacheStats handles GET /cache/stats
 func (cc *CacheController) GetCacheStats(c *gin.Context) {
 	stats := cc.statsService.GetStats(c.Request.Context())
@@ -140,6 +31,18 @@ func (cc *CacheController) GetCacheStats(c *gin.Context) {
 	})
 }
 
+// GetCacheMetrics handles GET /cache/metrics in the Prometheus text format
+func (cc *CacheController) GetCacheMetrics(c *gin.Context) {
+	var metrics []*CacheMetrics
+	if instrumented, ok := cc.cacheService.cache.(*InstrumentedCache); ok {
+		metrics = append(metrics, instrumented.Metrics())
+	}
+
+	c.Header("Content-Type", prometheusContentType)
+	c.Status(http.StatusOK)
+	WritePrometheus(c.Writer, metrics...)
+}
+
 // FlushCache handles POST /cache/flush
 func (cc *CacheController) FlushCache(c *gin.Context) {
 	err := cc.statsService.FlushCache(c.Request.Context())
@@ -159,21 +62,26 @@ func (cc *CacheController) InvalidatePattern(c *gin.Context) {
 		Pattern string `json:"pattern" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if bindErr := helpers.BindJSON(c, &request); bindErr != nil {
+		c.JSON(bindErr.Status, bindErr)
 		return
 	}
 
-	err := cc.statsService.InvalidatePattern(c.Request.Context(), request.Pattern)
+	deleted, err := cc.statsService.InvalidatePattern(c.Request.Context(), request.Pattern)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"message": "Cache pattern invalidated successfully",
 		"pattern": request.Pattern,
-	})
+	}
+	if deleted >= 0 {
+		response["deleted"] = deleted
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // SetCacheValue handles POST /cache/set
@@ -184,8 +92,8 @@ func (cc *CacheController) SetCacheValue(c *gin.Context) {
 		Expiration string      `json:"expiration"`
 	}
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if bindErr := helpers.BindJSON(c, &request); bindErr != nil {
+		c.JSON(bindErr.Status, bindErr)
 		return
 	}
 
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+	"fmt"
+	"time"
+)
+
+// EvictionPolicy selects which entry MemoryCache removes when it is full
+type EvictionPolicy string
+
+const (
+	// EvictionLRU removes the least recently used entry
+	EvictionLRU EvictionPolicy = "lru"
+	// EvictionLFU removes the least frequently used entry, oldest access first
+	EvictionLFU EvictionPolicy = "lfu"
+)
+
+// evictionTracker orders cache items for eviction
+type evictionTracker interface {
+	add(item *cacheItem)
+	touch(item *cacheItem)
+	remove(item *cacheItem)
+	victim() *cacheItem
+	reset()
+}
+
+// newEvictionTracker creates the tracker for a policy, defaulting to LRU
+func newEvictionTracker(policy EvictionPolicy) (evictionTracker, error) {
+	switch policy {
+	case "", EvictionLRU:
+		return &lruTracker{order: list.New()}, nil
+	case EvictionLFU:
+		return &lfuTracker{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported eviction policy: %s", policy)
+	}
+}
+
+// lruTracker keeps items in a list with the most recently used at the front
+type lruTracker struct {
+	order *list.List
+}
+
+func (t *lruTracker) add(item *cacheItem) {
+	item.element = t.order.PushFront(item)
+}
+
+func (t *lruTracker) touch(item *cacheItem) {
+	t.order.MoveToFront(item.element)
+}
+
+func (t *lruTracker) remove(item *cacheItem) {
+	t.order.Remove(item.element)
+	item.element = nil
+}
+
+func (t *lruTracker) victim() *cacheItem {
+	back := t.order.Back()
+	if back == nil {
+		return nil
+	}
+	return back.Value.(*cacheItem)
+}
+
+func (t *lruTracker) reset() {
+	t.order.Init()
+}
+
+// lfuTracker keeps items in a min-heap ordered by access count, breaking ties
+// by the oldest access
+type lfuTracker struct {
+	items lfuHeap
+}
+
+func (t *lfuTracker) add(item *cacheItem) {
+	item.frequency = 1
+	item.accessed = time.Now()
+	heap.Push(&t.items, item)
+}
+
+func (t *lfuTracker) touch(item *cacheItem) {
+	item.frequency++
+	item.accessed = time.Now()
+	heap.Fix(&t.items, item.index)
+}
+
+func (t *lfuTracker) remove(item *cacheItem) {
+	heap.Remove(&t.items, item.index)
+}
+
+func (t *lfuTracker) victim() *cacheItem {
+	if len(t.items) == 0 {
+		return nil
+	}
+	return t.items[0]
+}
+
+func (t *lfuTracker) reset() {
+	t.items = nil
+}
+
+// lfuHeap implements heap.Interface over cache items
+type lfuHeap []*cacheItem
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].frequency != h[j].frequency {
+		return h[i].frequency < h[j].frequency
+	}
+	return h[i].accessed.Before(h[j].accessed)
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	item := x.(*cacheItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*h = old[:n-1]
+	return item
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	appconfig "xanny-go/pkg/config"
+
 	"github.com/go-redis/redis/v8"
 )
 
@@ -16,26 +19,39 @@ type CacheType string
 const (
 	CacheTypeRedis  CacheType = "redis"
 	CacheTypeMemory CacheType = "memory"
+	CacheTypeTiered CacheType = "tiered"
 )
 
 // CacheConfig holds configuration for cache initialization
 type CacheConfig struct {
-	Type          CacheType
-	RedisAddr     string
-	RedisPassword string
-	RedisDB       int
-	DefaultTTL    time.Duration
-	MaxSize       int
-	Prefix        string
+	Type CacheType
+	// RedisClient, when set, is used instead of connecting with RedisOptions,
+	// so the cache shares the application's connection pool
+	RedisClient redis.UniversalClient
+	// RedisOptions describes the connection to open when RedisClient is nil,
+	// usually config.GetRedisOptions()
+	RedisOptions   *appconfig.RedisOptions
+	DefaultTTL     time.Duration
+	MaxSize        int
+	MaxBytes       int64
+	EvictionPolicy EvictionPolicy
+	Prefix         string
+	// L1TTL is how long a tiered cache keeps entries in process
+	L1TTL time.Duration
+	// SnapshotPath, when set, makes a memory cache restore its contents from
+	// this file on creation and save them there on Close
+	SnapshotPath string
 }
 
 // DefaultCacheConfig returns default cache configuration
 func DefaultCacheConfig() *CacheConfig {
 	return &CacheConfig{
-		Type:       CacheTypeMemory,
-		DefaultTTL: 5 * time.Minute,
-		MaxSize:    1000,
-		Prefix:     "cache:",
+		Type:           CacheTypeMemory,
+		DefaultTTL:     5 * time.Minute,
+		MaxSize:        1000,
+		EvictionPolicy: EvictionLRU,
+		Prefix:         "cache:",
+		L1TTL:          30 * time.Second,
 	}
 }
 
@@ -45,113 +61,193 @@ func NewCache(config *CacheConfig) (Cache, error) {
 		config = DefaultCacheConfig()
 	}
 
+	if _, err := newEvictionTracker(config.EvictionPolicy); err != nil {
+		return nil, err
+	}
+
 	opts := &CacheOptions{
-		DefaultTTL: config.DefaultTTL,
-		MaxSize:    config.MaxSize,
-		Prefix:     config.Prefix,
+		DefaultTTL:     config.DefaultTTL,
+		MaxSize:        config.MaxSize,
+		MaxBytes:       config.MaxBytes,
+		EvictionPolicy: config.EvictionPolicy,
+		Prefix:         config.Prefix,
 	}
 
 	switch config.Type {
 	case CacheTypeRedis:
 		return NewRedisCacheFromConfig(config, opts)
 	case CacheTypeMemory:
-		return NewMemoryCache(opts), nil
+		cache := NewMemoryCache(opts)
+		if config.SnapshotPath != "" {
+			if _, err := cache.EnableSnapshot(config.SnapshotPath); err != nil {
+				cache.Close()
+				return nil, err
+			}
+		}
+		return cache, nil
+	case CacheTypeTiered:
+		l2, err := NewRedisCacheFromConfig(config, opts)
+		if err != nil {
+			return nil, err
+		}
+		return NewTieredCache(NewMemoryCache(opts), l2.(*RedisCache), &TieredOptions{L1TTL: config.L1TTL}), nil
 	default:
 		return nil, fmt.Errorf("unsupported cache type: %s", config.Type)
 	}
 }
 
-// NewRedisCacheFromConfig creates a Redis cache from configuration
+// NewRedisCacheFromConfig creates a Redis cache from configuration, on the
+// shared client when one is given and otherwise on a client it opens and owns
 func NewRedisCacheFromConfig(config *CacheConfig, opts *CacheOptions) (Cache, error) {
-	if config.RedisAddr == "" {
-		return nil, fmt.Errorf("Redis address is required")
+	if config.RedisClient != nil {
+		return NewRedisCache(config.RedisClient, opts), nil
 	}
 
-	client := redis.NewClient(&redis.Options{
-		Addr:     config.RedisAddr,
-		Password: config.RedisPassword,
-		DB:       config.RedisDB,
-	})
+	if config.RedisOptions == nil {
+		return nil, fmt.Errorf("Redis client or options are required")
+	}
+
+	client, err := appconfig.NewRedisClient(*config.RedisOptions)
+	if err != nil {
+		return nil, err
+	}
 
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	return NewRedisCache(client, opts), nil
+	cache := NewRedisCache(client, opts)
+	cache.ownsClient = true
+	return cache, nil
 }
 
-// NewCacheFromEnv creates a cache instance from environment variables
+// NewCacheFromEnv creates a cache instance from CACHE_* environment variables
+// and fails on values it cannot parse. Redis-backed caches use
+// config.RedisClient once config.InitRedis has run, with its sentinel,
+// cluster and TLS settings; otherwise they connect to the comma-separated
+// REDIS_ADDR with REDIS_USERNAME, REDIS_PASS and REDIS_DB
 func NewCacheFromEnv() (Cache, error) {
 	config := DefaultCacheConfig()
+	config.RedisClient = appconfig.RedisClient
 
-	// Read cache type from environment
 	if cacheType := os.Getenv("CACHE_TYPE"); cacheType != "" {
 		config.Type = CacheType(cacheType)
 	}
 
-	// Read Redis configuration from environment
-	if redisAddr := os.Getenv("REDIS_ADDR"); redisAddr != "" {
-		config.RedisAddr = redisAddr
+	if config.RedisClient == nil && config.Type != CacheTypeMemory {
+		redisOptions := &appconfig.RedisOptions{
+			Addrs:    strings.Split(envOrDefault("REDIS_ADDR", "localhost:6379"), ","),
+			Username: os.Getenv("REDIS_USERNAME"),
+			Password: os.Getenv("REDIS_PASS"),
+		}
+		if err := parseEnv("REDIS_DB", strconv.Atoi, &redisOptions.DB); err != nil {
+			return nil, err
+		}
+		config.RedisOptions = redisOptions
 	}
 
-	if redisPass := os.Getenv("REDIS_PASS"); redisPass != "" {
-		config.RedisPassword = redisPass
+	if err := parseEnv("CACHE_DEFAULT_TTL", time.ParseDuration, &config.DefaultTTL); err != nil {
+		return nil, err
 	}
-
-	if redisDB := os.Getenv("REDIS_DB"); redisDB != "" {
-		if db, err := strconv.Atoi(redisDB); err == nil {
-			config.RedisDB = db
-		}
+	if err := parseEnv("CACHE_MAX_SIZE", strconv.Atoi, &config.MaxSize); err != nil {
+		return nil, err
+	}
+	if err := parseEnv("CACHE_MAX_BYTES", parseInt64, &config.MaxBytes); err != nil {
+		return nil, err
+	}
+	if err := parseEnv("CACHE_L1_TTL", time.ParseDuration, &config.L1TTL); err != nil {
+		return nil, err
 	}
 
-	// Read cache options from environment
-	if ttl := os.Getenv("CACHE_DEFAULT_TTL"); ttl != "" {
-		if duration, err := time.ParseDuration(ttl); err == nil {
-			config.DefaultTTL = duration
-		}
+	if policy := os.Getenv("CACHE_EVICTION_POLICY"); policy != "" {
+		config.EvictionPolicy = EvictionPolicy(policy)
 	}
+	config.Prefix = envOrDefault("CACHE_PREFIX", config.Prefix)
+	config.SnapshotPath = os.Getenv("CACHE_SNAPSHOT_PATH")
 
-	if maxSize := os.Getenv("CACHE_MAX_SIZE"); maxSize != "" {
-		if size, err := strconv.Atoi(maxSize); err == nil {
-			config.MaxSize = size
-		}
+	return NewCache(config)
+}
+
+// AppCacheConfig returns the cache configuration loaded by
+// config.InitConfig, sharing config.RedisClient
+func AppCacheConfig() *CacheConfig {
+	return &CacheConfig{
+		Type:           CacheType(appconfig.GetCacheType()),
+		RedisClient:    appconfig.RedisClient,
+		DefaultTTL:     appconfig.GetCacheDefaultTTL(),
+		MaxSize:        appconfig.GetCacheMaxSize(),
+		MaxBytes:       appconfig.GetCacheMaxBytes(),
+		EvictionPolicy: EvictionPolicy(appconfig.GetCacheEvictionPolicy()),
+		Prefix:         appconfig.GetCachePrefix(),
+		L1TTL:          appconfig.GetCacheL1TTL(),
+		SnapshotPath:   appconfig.GetCacheSnapshotPath(),
 	}
+}
 
-	if prefix := os.Getenv("CACHE_PREFIX"); prefix != "" {
-		config.Prefix = prefix
+// NewSerializerFromEnv creates a serializer from CACHE_CODEC (json, msgpack
+// or gob), CACHE_COMPRESSION (none, zstd or snappy) and
+// CACHE_COMPRESSION_THRESHOLD
+func NewSerializerFromEnv() (*Serializer, error) {
+	threshold := DefaultCompressThreshold
+	if err := parseEnv("CACHE_COMPRESSION_THRESHOLD", strconv.Atoi, &threshold); err != nil {
+		return nil, err
 	}
 
-	return NewCache(config)
+	return NewSerializer(os.Getenv("CACHE_CODEC"), os.Getenv("CACHE_COMPRESSION"), threshold)
 }
 
-// NewCacheManagerFromEnv creates a cache manager with caches from environment
+// NewCacheManagerFromEnv creates a cache manager with the cache from
+// NewCacheFromEnv as primary and a small memory cache as fallback
 func NewCacheManagerFromEnv() (*CacheManager, error) {
 	manager := NewCacheManager(nil)
 
-	// Create primary cache
 	primaryCache, err := NewCacheFromEnv()
 	if err != nil {
 		return nil, err
 	}
-
 	manager.RegisterCache("primary", primaryCache)
 
-	// Create memory cache as fallback
-	memoryCache := NewMemoryCache(&CacheOptions{
+	manager.RegisterCache("fallback", NewMemoryCache(&CacheOptions{
 		DefaultTTL: 1 * time.Minute,
 		MaxSize:    100,
 		Prefix:     "fallback:",
-	})
-
-	manager.RegisterCache("fallback", memoryCache)
+	}))
 
 	return manager, nil
 }
 
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func parseInt64(value string) (int64, error) {
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// parseEnv stores the parsed value of key in dest, leaving dest alone when
+// key is unset
+func parseEnv[T any](key string, parse func(string) (T, error), dest *T) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", key, value, err)
+	}
+	*dest = parsed
+	return nil
+}
+
 // CacheHealthChecker checks the health of cache instances
 type CacheHealthChecker struct {
 	cache Cache
@@ -189,11 +285,19 @@ func (chc *CacheHealthChecker) GetCacheStats(ctx context.Context) map[string]int
 		"type": "unknown",
 	}
 
+	if instrumented, ok := chc.cache.(*InstrumentedCache); ok {
+		stats["metrics"] = instrumented.Metrics().Snapshot()
+	}
+
 	// Try to get specific stats based on cache type
-	if _, ok := chc.cache.(*RedisCache); ok {
+	cache := unwrapCache(chc.cache)
+	if _, ok := cache.(*RedisCache); ok {
 		stats["type"] = "redis"
 		// Add Redis-specific stats if needed
-	} else if memoryCache, ok := chc.cache.(*MemoryCache); ok {
+	} else if tieredCache, ok := cache.(*TieredCache); ok {
+		stats["type"] = "tiered"
+		stats["stats"] = tieredCache.GetStats()
+	} else if memoryCache, ok := cache.(*MemoryCache); ok {
 		stats["type"] = "memory"
 		stats["stats"] = memoryCache.GetStats()
 	}
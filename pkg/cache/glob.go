@@ -0,0 +1,131 @@
+package cache
+
+import "strings"
+
+// MatchPattern reports whether key matches a glob pattern with the same
+// semantics as Redis KEYS and SCAN MATCH:
+//
+//	pattern  matches
+//	*        any sequence of characters, including none
+//	?        exactly one character
+//	[abc]    one character from the set; [^abc] negates it, [a-z] is a range
+//	\x       the character x literally
+//
+// Matching is byte-wise and case-sensitive. Every Cache implementation applies
+// patterns to keys without their configured prefix.
+func MatchPattern(pattern, key string) bool {
+	p, k := 0, 0
+	starP, starK := -1, 0
+
+	for k < len(key) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				starP, starK = p, k
+				p++
+				continue
+
+			case '?':
+				p++
+				k++
+				continue
+
+			case '[':
+				if matched, next := matchClass(pattern, p, key[k]); matched {
+					p = next
+					k++
+					continue
+				}
+
+			case '\\':
+				if p+1 < len(pattern) {
+					if pattern[p+1] == key[k] {
+						p += 2
+						k++
+						continue
+					}
+				} else if key[k] == '\\' {
+					p++
+					k++
+					continue
+				}
+
+			default:
+				if pattern[p] == key[k] {
+					p++
+					k++
+					continue
+				}
+			}
+		}
+
+		// Mismatch: let the last star absorb one more character
+		if starP < 0 {
+			return false
+		}
+		starK++
+		p, k = starP+1, starK
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchClass matches c against the bracket expression starting at
+// pattern[start] and returns the index just past it. Like Redis, an
+// unterminated class runs to the end of the pattern.
+func matchClass(pattern string, start int, c byte) (bool, int) {
+	i := start + 1
+	negate := i < len(pattern) && pattern[i] == '^'
+	if negate {
+		i++
+	}
+
+	matched := false
+	for i < len(pattern) && pattern[i] != ']' {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			if pattern[i+1] == c {
+				matched = true
+			}
+			i += 2
+
+		case i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']':
+			lo, hi := pattern[i], pattern[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			i += 3
+
+		default:
+			if pattern[i] == c {
+				matched = true
+			}
+			i++
+		}
+	}
+
+	if i < len(pattern) {
+		i++
+	}
+	return matched != negate, i
+}
+
+// EscapePattern escapes the glob metacharacters in s so it matches itself
+// literally, for example when invalidating a key that contains '*'.
+func EscapePattern(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
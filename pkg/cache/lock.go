@@ -0,0 +1,319 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrLockNotAcquired is returned when a lock is held elsewhere
+	ErrLockNotAcquired = errors.New("lock not acquired")
+
+	// ErrLockNotHeld is returned by Refresh and Release once the lock has
+	// expired or been taken over
+	ErrLockNotHeld = errors.New("lock not held")
+)
+
+// releaseScript deletes the lock only if it still holds our token
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// refreshScript extends the lock only if it still holds our token
+var refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// LockOptions configures a Locker
+type LockOptions struct {
+	// Prefix is prepended to lock names
+	Prefix string
+	// MinBackoff and MaxBackoff bound the jittered exponential delay between
+	// attempts in Acquire
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultLockOptions returns default lock options
+func DefaultLockOptions() *LockOptions {
+	return &LockOptions{
+		Prefix:     "lock:",
+		MinBackoff: 50 * time.Millisecond,
+		MaxBackoff: time.Second,
+	}
+}
+
+// lockBackend stores lock tokens atomically
+type lockBackend interface {
+	acquire(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	release(ctx context.Context, key, token string) (bool, error)
+}
+
+// Locker hands out named locks that expire after a TTL unless refreshed.
+// Each acquisition gets a random token, and only its holder can refresh or
+// release the lock
+type Locker struct {
+	backend lockBackend
+	opts    *LockOptions
+}
+
+// NewRedisLocker creates a locker on a Redis client. Refresh and release
+// compare tokens in Lua, so a holder whose lock expired cannot free a lock
+// someone else has since acquired
+func NewRedisLocker(client redis.UniversalClient, opts *LockOptions) *Locker {
+	return newLocker(&redisLockBackend{client: client}, opts)
+}
+
+// NewMemoryLocker creates a locker local to this process, for tests and
+// single-node setups
+func NewMemoryLocker(opts *LockOptions) *Locker {
+	return newLocker(&memoryLockBackend{locks: make(map[string]memoryLock)}, opts)
+}
+
+// NewLocker creates a locker backed by the same store as cache: Redis for
+// Redis and tiered caches, the process for memory caches
+func NewLocker(cache Cache, opts *LockOptions) (*Locker, error) {
+	switch c := unwrapCache(cache).(type) {
+	case *RedisCache:
+		return NewRedisLocker(c.client, opts), nil
+	case *TieredCache:
+		return NewRedisLocker(c.l2.client, opts), nil
+	case *MemoryCache:
+		return NewMemoryLocker(opts), nil
+	default:
+		return nil, fmt.Errorf("cache %T cannot back a locker", cache)
+	}
+}
+
+func newLocker(backend lockBackend, opts *LockOptions) *Locker {
+	defaults := DefaultLockOptions()
+	if opts == nil {
+		opts = defaults
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = defaults.MinBackoff
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(defaults.MaxBackoff, opts.MinBackoff)
+	}
+
+	return &Locker{backend: backend, opts: opts}
+}
+
+// TryAcquire takes the lock named name for ttl in a single attempt,
+// returning ErrLockNotAcquired if it is held elsewhere. ttl must be positive
+func (l *Locker) TryAcquire(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("lock %q: ttl must be positive, got %v", name, ttl)
+	}
+
+	lock := &Lock{
+		locker: l,
+		name:   name,
+		key:    l.opts.Prefix + name,
+		token:  uuid.NewString(),
+		ttl:    ttl,
+	}
+
+	acquired, err := l.backend.acquire(ctx, lock.key, lock.token, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, ErrLockNotAcquired
+	}
+	return lock, nil
+}
+
+// Acquire takes the lock named name for ttl, retrying with jittered
+// exponential backoff until it succeeds or ctx is done
+func (l *Locker) Acquire(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	backoff := l.opts.MinBackoff
+	for {
+		lock, err := l.TryAcquire(ctx, name, ttl)
+		if !errors.Is(err, ErrLockNotAcquired) {
+			return lock, err
+		}
+
+		timer := time.NewTimer(backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w: %w", ErrLockNotAcquired, ctx.Err())
+		case <-timer.C:
+		}
+
+		backoff = min(backoff*2, l.opts.MaxBackoff)
+	}
+}
+
+// Lock is a held lock
+type Lock struct {
+	locker *Locker
+	name   string
+	key    string
+	token  string
+
+	mu  sync.Mutex
+	ttl time.Duration
+}
+
+// Name returns the lock name
+func (lock *Lock) Name() string {
+	return lock.name
+}
+
+// Token returns the random value identifying this acquisition
+func (lock *Lock) Token() string {
+	return lock.token
+}
+
+// Refresh extends the lock to ttl from now, returning ErrLockNotHeld if it
+// was lost. ttl must be positive
+func (lock *Lock) Refresh(ctx context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("lock %q: ttl must be positive, got %v", lock.name, ttl)
+	}
+
+	held, err := lock.locker.backend.refresh(ctx, lock.key, lock.token, ttl)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return ErrLockNotHeld
+	}
+
+	lock.mu.Lock()
+	lock.ttl = ttl
+	lock.mu.Unlock()
+	return nil
+}
+
+// Release frees the lock, returning ErrLockNotHeld if it had already expired
+// or been taken over
+func (lock *Lock) Release(ctx context.Context) error {
+	held, err := lock.locker.backend.release(ctx, lock.key, lock.token)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// KeepAlive refreshes the lock every third of its TTL until ctx is done or
+// cancel is called. The returned context is cancelled as soon as the lock is
+// lost, so work guarded by it can stop.
+func (lock *Lock) KeepAlive(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		defer cancel()
+		for {
+			lock.mu.Lock()
+			ttl := lock.ttl
+			lock.mu.Unlock()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(ttl / 3):
+			}
+
+			if err := lock.Refresh(ctx, ttl); err != nil {
+				return
+			}
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// redisLockBackend keeps locks in Redis
+type redisLockBackend struct {
+	client redis.UniversalClient
+}
+
+func (b *redisLockBackend) acquire(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	return b.client.SetNX(ctx, key, token, ttl).Result()
+}
+
+func (b *redisLockBackend) refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	result, err := refreshScript.Run(ctx, b.client, []string{key}, token, ttl.Milliseconds()).Int64()
+	return result == 1, err
+}
+
+func (b *redisLockBackend) release(ctx context.Context, key, token string) (bool, error) {
+	result, err := releaseScript.Run(ctx, b.client, []string{key}, token).Int64()
+	return result == 1, err
+}
+
+// memoryLock is a lock held in process
+type memoryLock struct {
+	token   string
+	expires time.Time
+}
+
+// memoryLockBackend keeps locks in a map
+type memoryLockBackend struct {
+	mu    sync.Mutex
+	locks map[string]memoryLock
+}
+
+// heldLocked returns the live lock under key, if any
+func (b *memoryLockBackend) heldLocked(key string) (memoryLock, bool) {
+	lock, ok := b.locks[key]
+	if ok && !time.Now().Before(lock.expires) {
+		delete(b.locks, key)
+		return memoryLock{}, false
+	}
+	return lock, ok
+}
+
+func (b *memoryLockBackend) acquire(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, held := b.heldLocked(key); held {
+		return false, nil
+	}
+	b.locks[key] = memoryLock{token: token, expires: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (b *memoryLockBackend) refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lock, held := b.heldLocked(key)
+	if !held || lock.token != token {
+		return false, nil
+	}
+	b.locks[key] = memoryLock{token: token, expires: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (b *memoryLockBackend) release(ctx context.Context, key, token string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lock, held := b.heldLocked(key)
+	if !held || lock.token != token {
+		return false, nil
+	}
+	delete(b.locks, key)
+	return true, nil
+}
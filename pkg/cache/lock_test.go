@@ -0,0 +1,123 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"xanny-go/pkg/cache"
+)
+
+func newLockers(t *testing.T) map[string]*cache.Locker {
+	opts := func() *cache.LockOptions {
+		return &cache.LockOptions{Prefix: "lock:", MinBackoff: 10 * time.Millisecond, MaxBackoff: 40 * time.Millisecond}
+	}
+	return map[string]*cache.Locker{
+		"memory": cache.NewMemoryLocker(opts()),
+		"redis":  cache.NewRedisLocker(newRedisClient(t), opts()),
+	}
+}
+
+func TestLockReleaseAfterExpiry(t *testing.T) {
+	for name, locker := range newLockers(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			first, err := locker.TryAcquire(ctx, "job", 100*time.Millisecond)
+			if err != nil {
+				t.Fatalf("TryAcquire: %v", err)
+			}
+			time.Sleep(200 * time.Millisecond)
+
+			second, err := locker.TryAcquire(ctx, "job", time.Minute)
+			if err != nil {
+				t.Fatalf("TryAcquire after expiry: %v", err)
+			}
+
+			if err := first.Refresh(ctx, time.Minute); !errors.Is(err, cache.ErrLockNotHeld) {
+				t.Errorf("Refresh of an expired lock = %v, want ErrLockNotHeld", err)
+			}
+			if err := first.Release(ctx); !errors.Is(err, cache.ErrLockNotHeld) {
+				t.Errorf("Release of an expired lock = %v, want ErrLockNotHeld", err)
+			}
+
+			if _, err := locker.TryAcquire(ctx, "job", time.Minute); !errors.Is(err, cache.ErrLockNotAcquired) {
+				t.Errorf("TryAcquire while the new holder has the lock = %v, want ErrLockNotAcquired", err)
+			}
+			if err := second.Release(ctx); err != nil {
+				t.Errorf("Release by the new holder: %v", err)
+			}
+		})
+	}
+}
+
+func TestLockAcquireWaitsForRelease(t *testing.T) {
+	for name, locker := range newLockers(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			held, err := locker.TryAcquire(ctx, "job", time.Minute)
+			if err != nil {
+				t.Fatalf("TryAcquire: %v", err)
+			}
+			time.AfterFunc(100*time.Millisecond, func() { held.Release(ctx) })
+
+			waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+
+			lock, err := locker.Acquire(waitCtx, "job", time.Minute)
+			if err != nil {
+				t.Fatalf("Acquire: %v", err)
+			}
+			lock.Release(ctx)
+		})
+	}
+}
+
+func TestLockAcquireStopsOnCancel(t *testing.T) {
+	for name, locker := range newLockers(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			held, err := locker.TryAcquire(ctx, "job", time.Minute)
+			if err != nil {
+				t.Fatalf("TryAcquire: %v", err)
+			}
+			defer held.Release(ctx)
+
+			waitCtx, cancel := context.WithTimeout(ctx, 150*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, err = locker.Acquire(waitCtx, "job", time.Minute)
+			if !errors.Is(err, cache.ErrLockNotAcquired) || !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Acquire = %v, want ErrLockNotAcquired and context.DeadlineExceeded", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Acquire returned %v after ctx was done", elapsed)
+			}
+		})
+	}
+}
+
+func TestLockRejectsNonPositiveTTL(t *testing.T) {
+	locker := cache.NewMemoryLocker(nil)
+	ctx := context.Background()
+
+	for _, ttl := range []time.Duration{0, -time.Second} {
+		if _, err := locker.TryAcquire(ctx, "job", ttl); err == nil {
+			t.Errorf("TryAcquire with ttl %v succeeded", ttl)
+		}
+	}
+
+	lock, err := locker.TryAcquire(ctx, "job", time.Minute)
+	if err != nil {
+		t.Fatalf("TryAcquire: %v", err)
+	}
+	if err := lock.Refresh(ctx, 0); err == nil {
+		t.Error("Refresh with ttl 0 succeeded")
+	}
+	if err := lock.Release(ctx); err != nil {
+		t.Errorf("Release after a rejected Refresh: %v", err)
+	}
+}
@@ -1,32 +1,77 @@
 package cache
 
 import (
+	"container/list"
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 )
 
 // cacheItem represents an item stored in memory cache
 type cacheItem struct {
+	key        string
 	value      []byte
 	expiration time.Time
+	tags       []string
+
+	// eviction bookkeeping, owned by the evictionTracker
+	element   *list.Element
+	index     int
+	frequency uint64
+	accessed  time.Time
+}
+
+// expired reports whether the item has passed its expiration time
+func (item *cacheItem) expired(now time.Time) bool {
+	return !item.expiration.IsZero() && now.After(item.expiration)
+}
+
+// size is the number of bytes the item counts against MaxBytes
+func (item *cacheItem) size() int64 {
+	return int64(len(item.key) + len(item.value))
 }
 
-// MemoryCache implements the Cache interface using in-memory storage
+// MemoryCache implements the Cache interface using in-memory storage. It is
+// bounded by MaxSize entries and MaxBytes bytes, evicting according to
+// EvictionPolicy once either limit is reached
 type MemoryCache struct {
-	data sync.Map
-	opts *CacheOptions
+	mu      sync.Mutex
+	items   map[string]*cacheItem
+	tags    map[string]map[string]struct{}
+	tracker evictionTracker
+	bytes   int64
+	opts    *CacheOptions
+
+	evictions   uint64
+	expirations uint64
+
+	stop      chan struct{}
+	closeOnce sync.Once
+
+	// snapshotPath, when set, is where Close saves the contents
+	snapshotPath string
 }
 
-// NewMemoryCache creates a new in-memory cache instance
+// NewMemoryCache creates a new in-memory cache instance. An unknown eviction
+// policy falls back to LRU
 func NewMemoryCache(opts *CacheOptions) *MemoryCache {
 	if opts == nil {
 		opts = DefaultCacheOptions()
 	}
 
+	tracker, err := newEvictionTracker(opts.EvictionPolicy)
+	if err != nil {
+		tracker, _ = newEvictionTracker(EvictionLRU)
+	}
+
 	cache := &MemoryCache{
-		opts: opts,
+		items:   make(map[string]*cacheItem),
+		tags:    make(map[string]map[string]struct{}),
+		tracker: tracker,
+		opts:    opts,
+		stop:    make(chan struct{}),
 	}
 
 	// Start cleanup goroutine
@@ -39,49 +84,136 @@ func NewMemoryCache(opts *CacheOptions) *MemoryCache {
 func (mc *MemoryCache) Get(ctx context.Context, key string) ([]byte, error) {
 	key = mc.opts.Prefix + key
 
-	value, exists := mc.data.Load(key)
-	if !exists {
-		return nil, fmt.Errorf("key not found: %s", key)
-	}
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
 
-	item, ok := value.(*cacheItem)
-	if !ok {
-		return nil, fmt.Errorf("invalid cache item type for key: %s", key)
+	item, exists := mc.items[key]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, key)
 	}
 
 	// Check if item has expired
-	if !item.expiration.IsZero() && time.Now().After(item.expiration) {
-		mc.data.Delete(key)
-		return nil, fmt.Errorf("key expired: %s", key)
+	if item.expired(time.Now()) {
+		mc.removeLocked(item)
+		mc.expirations++
+		return nil, fmt.Errorf("%w: %s has expired", ErrKeyNotFound, key)
 	}
 
+	mc.tracker.touch(item)
 	return item.value, nil
 }
 
 // Set stores a value in memory cache with expiration
 func (mc *MemoryCache) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
-	key = mc.opts.Prefix + key
+	item, err := mc.newItem(key, value, expiration)
+	if err != nil {
+		return err
+	}
 
-	var expirationTime time.Time
-	if expiration > 0 {
-		expirationTime = time.Now().Add(expiration)
-	} else if mc.opts.DefaultTTL > 0 {
-		expirationTime = time.Now().Add(mc.opts.DefaultTTL)
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if existing, exists := mc.items[item.key]; exists {
+		mc.removeLocked(existing)
 	}
+	mc.insertLocked(item)
+	return nil
+}
 
-	item := &cacheItem{
-		value:      value,
-		expiration: expirationTime,
+// SetNX stores a value only if the key is absent or expired and reports
+// whether it was stored
+func (mc *MemoryCache) SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	item, err := mc.newItem(key, value, expiration)
+	if err != nil {
+		return false, err
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if existing, exists := mc.items[item.key]; exists {
+		if !existing.expired(time.Now()) {
+			return false, nil
+		}
+		mc.removeLocked(existing)
+		mc.expirations++
 	}
+	mc.insertLocked(item)
+	return true, nil
+}
+
+// SetXX stores a value only if the key is present and not expired and
+// reports whether it was stored
+func (mc *MemoryCache) SetXX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	item, err := mc.newItem(key, value, expiration)
+	if err != nil {
+		return false, err
+	}
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
 
-	mc.data.Store(key, item)
+	existing, exists := mc.items[item.key]
+	if !exists {
+		return false, nil
+	}
+	mc.removeLocked(existing)
+	if existing.expired(time.Now()) {
+		mc.expirations++
+		return false, nil
+	}
+	mc.insertLocked(item)
+	return true, nil
+}
+
+// SetWithTags stores a value and records it under each tag
+func (mc *MemoryCache) SetWithTags(ctx context.Context, key string, value []byte, expiration time.Duration, tags ...string) error {
+	item, err := mc.newItem(key, value, expiration)
+	if err != nil {
+		return err
+	}
+	item.tags = tags
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if existing, exists := mc.items[item.key]; exists {
+		mc.removeLocked(existing)
+	}
+	mc.insertLocked(item)
 	return nil
 }
 
+// InvalidateTags removes every entry carrying any of the tags and returns the
+// number of entries removed
+func (mc *MemoryCache) InvalidateTags(ctx context.Context, tags ...string) (int64, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	var deleted int64
+	for _, tag := range tags {
+		for key := range mc.tags[tag] {
+			if item, exists := mc.items[key]; exists {
+				mc.removeLocked(item)
+				deleted++
+			}
+		}
+		delete(mc.tags, tag)
+	}
+
+	return deleted, nil
+}
+
 // Delete removes a value from memory cache
 func (mc *MemoryCache) Delete(ctx context.Context, key string) error {
 	key = mc.opts.Prefix + key
-	mc.data.Delete(key)
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if item, exists := mc.items[key]; exists {
+		mc.removeLocked(item)
+	}
 	return nil
 }
 
@@ -89,19 +221,18 @@ func (mc *MemoryCache) Delete(ctx context.Context, key string) error {
 func (mc *MemoryCache) Exists(ctx context.Context, key string) (bool, error) {
 	key = mc.opts.Prefix + key
 
-	value, exists := mc.data.Load(key)
-	if !exists {
-		return false, nil
-	}
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
 
-	item, ok := value.(*cacheItem)
-	if !ok {
+	item, exists := mc.items[key]
+	if !exists {
 		return false, nil
 	}
 
 	// Check if item has expired
-	if !item.expiration.IsZero() && time.Now().After(item.expiration) {
-		mc.data.Delete(key)
+	if item.expired(time.Now()) {
+		mc.removeLocked(item)
+		mc.expirations++
 		return false, nil
 	}
 
@@ -110,13 +241,100 @@ func (mc *MemoryCache) Exists(ctx context.Context, key string) (bool, error) {
 
 // Flush removes all keys from memory cache
 func (mc *MemoryCache) Flush(ctx context.Context) error {
-	mc.data = sync.Map{}
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.items = make(map[string]*cacheItem)
+	mc.tags = make(map[string]map[string]struct{})
+	mc.tracker.reset()
+	mc.bytes = 0
 	return nil
 }
 
-// Close closes the memory cache (no-op for memory cache)
+// Close stops the background cleanup and saves a snapshot if EnableSnapshot
+// was called
 func (mc *MemoryCache) Close() error {
-	return nil
+	var err error
+	mc.closeOnce.Do(func() {
+		close(mc.stop)
+		if mc.snapshotPath != "" {
+			err = mc.SaveSnapshot(mc.snapshotPath)
+		}
+	})
+	return err
+}
+
+// newItem builds an item for key, applying the prefix and default TTL
+func (mc *MemoryCache) newItem(key string, value []byte, expiration time.Duration) (*cacheItem, error) {
+	if expiration <= 0 {
+		expiration = mc.opts.DefaultTTL
+	}
+
+	item := &cacheItem{
+		key:   mc.opts.Prefix + key,
+		value: value,
+	}
+	if expiration > 0 {
+		item.expiration = time.Now().Add(expiration)
+	}
+
+	if mc.opts.MaxBytes > 0 && item.size() > mc.opts.MaxBytes {
+		return nil, fmt.Errorf("value for key %s is larger than the cache byte limit", item.key)
+	}
+
+	return item, nil
+}
+
+// insertLocked adds an item, first evicting existing entries until it fits.
+// The new item is tracked only afterwards so LFU cannot pick it as its own
+// victim. mc.mu must be held
+func (mc *MemoryCache) insertLocked(item *cacheItem) {
+	mc.items[item.key] = item
+	mc.bytes += item.size()
+
+	for mc.overLimitLocked() {
+		victim := mc.tracker.victim()
+		if victim == nil {
+			break
+		}
+		mc.removeLocked(victim)
+		mc.evictions++
+	}
+
+	mc.tracker.add(item)
+
+	for _, tag := range item.tags {
+		keys, exists := mc.tags[tag]
+		if !exists {
+			keys = make(map[string]struct{})
+			mc.tags[tag] = keys
+		}
+		keys[item.key] = struct{}{}
+	}
+}
+
+// overLimitLocked reports whether either size limit is exceeded. mc.mu must
+// be held
+func (mc *MemoryCache) overLimitLocked() bool {
+	if mc.opts.MaxSize > 0 && len(mc.items) > mc.opts.MaxSize {
+		return true
+	}
+	return mc.opts.MaxBytes > 0 && mc.bytes > mc.opts.MaxBytes
+}
+
+// removeLocked drops an item from the map, tracker and tag index. mc.mu must
+// be held
+func (mc *MemoryCache) removeLocked(item *cacheItem) {
+	delete(mc.items, item.key)
+	mc.tracker.remove(item)
+	mc.bytes -= item.size()
+
+	for _, tag := range item.tags {
+		delete(mc.tags[tag], item.key)
+		if len(mc.tags[tag]) == 0 {
+			delete(mc.tags, tag)
+		}
+	}
 }
 
 // cleanup periodically removes expired items
@@ -124,91 +342,92 @@ func (mc *MemoryCache) cleanup() {
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		now := time.Now()
-		mc.data.Range(func(key, value interface{}) bool {
-			item, ok := value.(*cacheItem)
-			if !ok {
-				mc.data.Delete(key)
-				return true
-			}
+	for {
+		select {
+		case <-mc.stop:
+			return
+		case <-ticker.C:
+		}
 
-			if !item.expiration.IsZero() && now.After(item.expiration) {
-				mc.data.Delete(key)
+		now := time.Now()
+		mc.mu.Lock()
+		for _, item := range mc.items {
+			if item.expired(now) {
+				mc.removeLocked(item)
+				mc.expirations++
 			}
-
-			return true
-		})
+		}
+		mc.mu.Unlock()
 	}
 }
 
 // GetStats returns cache statistics
 func (mc *MemoryCache) GetStats() map[string]interface{} {
-	count := 0
-	mc.data.Range(func(key, value interface{}) bool {
-		count++
-		return true
-	})
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	policy := mc.opts.EvictionPolicy
+	if policy == "" {
+		policy = EvictionLRU
+	}
 
 	return map[string]interface{}{
-		"size":     count,
-		"max_size": mc.opts.MaxSize,
+		"size":            len(mc.items),
+		"max_size":        mc.opts.MaxSize,
+		"bytes":           mc.bytes,
+		"max_bytes":       mc.opts.MaxBytes,
+		"eviction_policy": policy,
+		"tags":            len(mc.tags),
+		"evictions":       mc.evictions,
+		"expirations":     mc.expirations,
 	}
 }
 
+// Evictions returns how many entries have been evicted to stay within
+// MaxSize and MaxBytes
+func (mc *MemoryCache) Evictions() uint64 {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.evictions
+}
+
 // GetKeys returns all keys in the cache
 func (mc *MemoryCache) GetKeys() []string {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
 	var keys []string
-	mc.data.Range(func(key, value interface{}) bool {
-		if keyStr, ok := key.(string); ok {
-			// Remove prefix for consistency
-			if len(keyStr) > len(mc.opts.Prefix) {
-				keys = append(keys, keyStr[len(mc.opts.Prefix):])
-			}
+	for key := range mc.items {
+		// Remove prefix for consistency
+		if len(key) > len(mc.opts.Prefix) {
+			keys = append(keys, key[len(mc.opts.Prefix):])
 		}
-		return true
-	})
+	}
 	return keys
 }
 
-// InvalidatePattern removes all keys matching a pattern
+// InvalidatePattern removes all keys matching a glob pattern, see MatchPattern
 func (mc *MemoryCache) InvalidatePattern(ctx context.Context, pattern string) error {
-	// Simple pattern matching for memory cache
-	// This is a basic implementation - you might want to use regex for more complex patterns
-	pattern = mc.opts.Prefix + pattern
-
-	var keysToDelete []interface{}
-	mc.data.Range(func(key, value interface{}) bool {
-		if keyStr, ok := key.(string); ok {
-			// Simple contains check - you can implement more sophisticated pattern matching
-			if contains(keyStr, pattern) {
-				keysToDelete = append(keysToDelete, key)
-			}
-		}
-		return true
-	})
-
-	for _, key := range keysToDelete {
-		mc.data.Delete(key)
-	}
-
-	return nil
+	_, err := mc.DeletePattern(ctx, pattern)
+	return err
 }
 
-// contains checks if a string contains another string (simple pattern matching)
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr ||
-		(len(s) > len(substr) && (s[:len(substr)] == substr ||
-			s[len(s)-len(substr):] == substr ||
-			containsSubstring(s, substr))))
-}
+// DeletePattern removes all keys matching a glob pattern and returns how many
+// were deleted
+func (mc *MemoryCache) DeletePattern(ctx context.Context, pattern string) (int64, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
 
-// containsSubstring checks if a string contains a substring
-func containsSubstring(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
+	var deleted int64
+	for key, item := range mc.items {
+		if !strings.HasPrefix(key, mc.opts.Prefix) {
+			continue
+		}
+		if MatchPattern(pattern, key[len(mc.opts.Prefix):]) {
+			mc.removeLocked(item)
+			deleted++
 		}
 	}
-	return false
+
+	return deleted, nil
 }
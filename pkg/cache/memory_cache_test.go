@@ -0,0 +1,97 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"xanny-go/pkg/cache"
+	"xanny-go/pkg/cache/cachetest"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// newRedisClient starts a miniredis server whose clock follows real time, so
+// TTLs expire while the conformance checks wait
+func newRedisClient(t *testing.T) *redis.Client {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+
+		last := time.Now()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				server.FastForward(now.Sub(last))
+				last = now
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		close(stop)
+		<-done
+	})
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestCacheConformance(t *testing.T) {
+	tests := []struct {
+		name     string
+		newCache func(t *testing.T) cache.Cache
+	}{
+		{
+			name: "memory",
+			newCache: func(t *testing.T) cache.Cache {
+				return cache.NewMemoryCache(nil)
+			},
+		},
+		{
+			name: "instrumented memory",
+			newCache: func(t *testing.T) cache.Cache {
+				return cache.Instrument("test", cache.NewMemoryCache(nil))
+			},
+		},
+		{
+			name: "redis",
+			newCache: func(t *testing.T) cache.Cache {
+				return cache.NewRedisCache(newRedisClient(t), &cache.CacheOptions{Prefix: "test:"})
+			},
+		},
+		{
+			name: "tiered",
+			newCache: func(t *testing.T) cache.Cache {
+				l1 := cache.NewMemoryCache(nil)
+				l2 := cache.NewRedisCache(newRedisClient(t), &cache.CacheOptions{Prefix: "test:"})
+				return cache.NewTieredCache(l1, l2, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := tt.newCache(t)
+			t.Cleanup(func() { c.Close() })
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			if err := cachetest.TestCache(ctx, c); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
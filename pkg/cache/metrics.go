@@ -0,0 +1,383 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Operation names used to label latency and error metrics
+const (
+	OpGet            = "get"
+	OpSet            = "set"
+	OpSetNX          = "set_nx"
+	OpSetXX          = "set_xx"
+	OpSetWithTags    = "set_with_tags"
+	OpDelete         = "delete"
+	OpExists         = "exists"
+	OpFlush          = "flush"
+	OpDeletePattern  = "delete_pattern"
+	OpInvalidateTags = "invalidate_tags"
+)
+
+// cacheOperations lists every operation an InstrumentedCache records
+var cacheOperations = []string{OpGet, OpSet, OpSetNX, OpSetXX, OpSetWithTags, OpDelete, OpExists, OpFlush, OpDeletePattern, OpInvalidateTags}
+
+// latencyBuckets are the histogram upper bounds in seconds, from a local
+// memory hit up to a slow network call
+var latencyBuckets = []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// latencyHistogram is a lock-free histogram over latencyBuckets
+type latencyHistogram struct {
+	buckets [14]atomic.Uint64 // one per bucket plus +Inf
+	count   atomic.Uint64
+	sum     atomic.Int64 // nanoseconds
+}
+
+// observe records one operation that took d
+func (h *latencyHistogram) observe(d time.Duration) {
+	seconds := d.Seconds()
+	i := 0
+	for i < len(latencyBuckets) && seconds > latencyBuckets[i] {
+		i++
+	}
+	h.buckets[i].Add(1)
+	h.count.Add(1)
+	h.sum.Add(int64(d))
+}
+
+// cumulative returns the count of observations at or below each bucket,
+// ending with +Inf
+func (h *latencyHistogram) cumulative() []uint64 {
+	counts := make([]uint64, len(latencyBuckets)+1)
+	var total uint64
+	for i := range counts {
+		total += h.buckets[i].Load()
+		counts[i] = total
+	}
+	return counts
+}
+
+// quantile estimates the q-th quantile in seconds as the upper bound of the
+// bucket it falls in
+func (h *latencyHistogram) quantile(q float64) float64 {
+	counts := h.cumulative()
+	total := counts[len(counts)-1]
+	if total == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(q * float64(total)))
+	for i, count := range counts[:len(latencyBuckets)] {
+		if count >= rank {
+			return latencyBuckets[i]
+		}
+	}
+	return math.Inf(1)
+}
+
+// operationMetrics holds the latency and error count of one operation
+type operationMetrics struct {
+	latency latencyHistogram
+	errors  atomic.Uint64
+}
+
+// CacheMetrics records what happens to one named cache. It is safe for
+// concurrent use
+type CacheMetrics struct {
+	name  string
+	cache Cache
+
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+	sets    atomic.Uint64
+	deletes atomic.Uint64
+
+	operations map[string]*operationMetrics
+}
+
+// newCacheMetrics creates empty metrics for every cache operation
+func newCacheMetrics(name string, cache Cache) *CacheMetrics {
+	metrics := &CacheMetrics{
+		name:       name,
+		cache:      cache,
+		operations: make(map[string]*operationMetrics, len(cacheOperations)),
+	}
+	for _, op := range cacheOperations {
+		metrics.operations[op] = &operationMetrics{}
+	}
+	return metrics
+}
+
+// Name returns the cache name the metrics are labeled with
+func (m *CacheMetrics) Name() string {
+	return m.name
+}
+
+// Evictions returns how many entries the cache has evicted, or zero when it
+// does not evict or cannot tell
+func (m *CacheMetrics) Evictions() uint64 {
+	if counter, ok := m.cache.(interface{ Evictions() uint64 }); ok {
+		return counter.Evictions()
+	}
+	return 0
+}
+
+// Errors returns the number of failed operations, not counting misses
+func (m *CacheMetrics) Errors() uint64 {
+	var errs uint64
+	for _, op := range m.operations {
+		errs += op.errors.Load()
+	}
+	return errs
+}
+
+// observe records the latency and outcome of one operation
+func (m *CacheMetrics) observe(op string, start time.Time, err error) {
+	metrics := m.operations[op]
+	metrics.latency.observe(time.Since(start))
+	if err != nil {
+		metrics.errors.Add(1)
+	}
+}
+
+// Snapshot returns the metrics in a form suitable for the stats endpoint
+func (m *CacheMetrics) Snapshot() map[string]interface{} {
+	hits, misses := m.hits.Load(), m.misses.Load()
+
+	ratio := 0.0
+	if total := hits + misses; total > 0 {
+		ratio = float64(hits) / float64(total)
+	}
+
+	operations := make(map[string]interface{}, len(m.operations))
+	for name, op := range m.operations {
+		count := op.latency.count.Load()
+		if count == 0 {
+			continue
+		}
+
+		operations[name] = map[string]interface{}{
+			"count":  count,
+			"errors": op.errors.Load(),
+			"avg_ms": float64(op.latency.sum.Load()) / float64(count) / float64(time.Millisecond),
+			"p50_ms": op.latency.quantile(0.5) * 1000,
+			"p99_ms": op.latency.quantile(0.99) * 1000,
+		}
+	}
+
+	return map[string]interface{}{
+		"name":       m.name,
+		"hits":       hits,
+		"misses":     misses,
+		"hit_ratio":  ratio,
+		"sets":       m.sets.Load(),
+		"deletes":    m.deletes.Load(),
+		"evictions":  m.Evictions(),
+		"errors":     m.Errors(),
+		"operations": operations,
+	}
+}
+
+// InstrumentedCache wraps a Cache and records hits, misses, writes, errors
+// and latency for every operation. CacheManager.RegisterCache wraps caches
+// automatically. It has the optional SetNX, SetXX and tag methods whatever it
+// wraps, so check for support with AsTaggedCache and AsExistingSetter rather
+// than a type assertion
+type InstrumentedCache struct {
+	cache   Cache
+	metrics *CacheMetrics
+}
+
+// Instrument wraps cache so its operations are recorded under name
+func Instrument(name string, cache Cache) *InstrumentedCache {
+	return &InstrumentedCache{
+		cache:   cache,
+		metrics: newCacheMetrics(name, cache),
+	}
+}
+
+// Unwrap returns the underlying cache
+func (ic *InstrumentedCache) Unwrap() Cache {
+	return ic.cache
+}
+
+// unwrapCache returns the cache beneath any instrumentation, for code that
+// looks for a concrete implementation
+func unwrapCache(cache Cache) Cache {
+	if instrumented, ok := cache.(*InstrumentedCache); ok {
+		return instrumented.Unwrap()
+	}
+	return cache
+}
+
+// Metrics returns the recorded metrics
+func (ic *InstrumentedCache) Metrics() *CacheMetrics {
+	return ic.metrics
+}
+
+// Get retrieves a value, counting it as a hit or a miss
+func (ic *InstrumentedCache) Get(ctx context.Context, key string) ([]byte, error) {
+	start := time.Now()
+	value, err := ic.cache.Get(ctx, key)
+
+	failure := err
+	if err == nil {
+		ic.metrics.hits.Add(1)
+	} else {
+		ic.metrics.misses.Add(1)
+		if errors.Is(err, ErrKeyNotFound) {
+			failure = nil
+		}
+	}
+
+	ic.metrics.observe(OpGet, start, failure)
+	return value, err
+}
+
+// Set stores a value
+func (ic *InstrumentedCache) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	start := time.Now()
+	err := ic.cache.Set(ctx, key, value, expiration)
+	if err == nil {
+		ic.metrics.sets.Add(1)
+	}
+
+	ic.metrics.observe(OpSet, start, err)
+	return err
+}
+
+// SetNX stores a value only if the key does not exist
+func (ic *InstrumentedCache) SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	setter, ok := ic.cache.(AtomicSetter)
+	if !ok {
+		return false, fmt.Errorf("cache %T does not support SetNX", ic.cache)
+	}
+
+	start := time.Now()
+	stored, err := setter.SetNX(ctx, key, value, expiration)
+	if stored {
+		ic.metrics.sets.Add(1)
+	}
+
+	ic.metrics.observe(OpSetNX, start, err)
+	return stored, err
+}
+
+// SetXX stores a value only if the key exists
+func (ic *InstrumentedCache) SetXX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	setter, ok := ic.cache.(ExistingSetter)
+	if !ok {
+		return false, fmt.Errorf("cache %T does not support SetXX", ic.cache)
+	}
+
+	start := time.Now()
+	stored, err := setter.SetXX(ctx, key, value, expiration)
+	if stored {
+		ic.metrics.sets.Add(1)
+	}
+
+	ic.metrics.observe(OpSetXX, start, err)
+	return stored, err
+}
+
+// SetWithTags stores a value under tags
+func (ic *InstrumentedCache) SetWithTags(ctx context.Context, key string, value []byte, expiration time.Duration, tags ...string) error {
+	start := time.Now()
+	err := setTagged(ctx, ic.cache, key, value, expiration, tags)
+	if err == nil {
+		ic.metrics.sets.Add(1)
+	}
+
+	ic.metrics.observe(OpSetWithTags, start, err)
+	return err
+}
+
+// InvalidateTags removes every entry carrying any of the tags
+func (ic *InstrumentedCache) InvalidateTags(ctx context.Context, tags ...string) (int64, error) {
+	tagged, ok := ic.cache.(TaggedCache)
+	if !ok {
+		return 0, ErrTagsNotSupported
+	}
+
+	start := time.Now()
+	deleted, err := tagged.InvalidateTags(ctx, tags...)
+	ic.metrics.deletes.Add(uint64(max(deleted, 0)))
+
+	ic.metrics.observe(OpInvalidateTags, start, err)
+	return deleted, err
+}
+
+// Delete removes a value
+func (ic *InstrumentedCache) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := ic.cache.Delete(ctx, key)
+	if err == nil {
+		ic.metrics.deletes.Add(1)
+	}
+
+	ic.metrics.observe(OpDelete, start, err)
+	return err
+}
+
+// Exists checks if a key exists
+func (ic *InstrumentedCache) Exists(ctx context.Context, key string) (bool, error) {
+	start := time.Now()
+	exists, err := ic.cache.Exists(ctx, key)
+
+	ic.metrics.observe(OpExists, start, err)
+	return exists, err
+}
+
+// Flush removes all keys
+func (ic *InstrumentedCache) Flush(ctx context.Context) error {
+	start := time.Now()
+	err := ic.cache.Flush(ctx)
+
+	ic.metrics.observe(OpFlush, start, err)
+	return err
+}
+
+// InvalidatePattern removes all keys matching a glob pattern
+func (ic *InstrumentedCache) InvalidatePattern(ctx context.Context, pattern string) error {
+	_, err := ic.DeletePattern(ctx, pattern)
+	return err
+}
+
+// DeletePattern removes all keys matching a glob pattern and returns how many
+// were deleted, or -1 when the underlying cache cannot count them
+func (ic *InstrumentedCache) DeletePattern(ctx context.Context, pattern string) (int64, error) {
+	start := time.Now()
+
+	var deleted int64 = -1
+	var err error
+	if deleter, ok := ic.cache.(PatternDeleter); ok {
+		deleted, err = deleter.DeletePattern(ctx, pattern)
+		ic.metrics.deletes.Add(uint64(max(deleted, 0)))
+	} else {
+		err = ic.cache.InvalidatePattern(ctx, pattern)
+	}
+
+	ic.metrics.observe(OpDeletePattern, start, err)
+	return deleted, err
+}
+
+// Close closes the underlying cache
+func (ic *InstrumentedCache) Close() error {
+	return ic.cache.Close()
+}
+
+// GetStats returns the underlying cache's stats, if it has any, together
+// with the recorded metrics
+func (ic *InstrumentedCache) GetStats() map[string]interface{} {
+	stats := map[string]interface{}{
+		"metrics": ic.metrics.Snapshot(),
+	}
+	if provider, ok := ic.cache.(interface{ GetStats() map[string]interface{} }); ok {
+		stats["cache"] = provider.GetStats()
+	}
+	return stats
+}
@@ -2,11 +2,11 @@ package cache
 
 import (
 	"bytes"
-	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -23,6 +23,9 @@ type CacheMiddlewareOptions struct {
 	ExcludeHeaders []string
 	IncludeMethods []string
 	ExcludeMethods []string
+	// Serializer encodes stored responses; MessagePack keeps bodies as raw
+	// bytes instead of base64
+	Serializer *Serializer
 }
 
 // DefaultCacheMiddlewareOptions returns default options for cache middleware
@@ -34,17 +37,29 @@ func DefaultCacheMiddlewareOptions(cache Cache) *CacheMiddlewareOptions {
 		ExcludeMethods: []string{"POST", "PUT", "DELETE", "PATCH"},
 		IncludeHeaders: []string{"Accept", "Accept-Language"},
 		ExcludeHeaders: []string{"Authorization", "Cookie"},
+		Serializer:     &Serializer{Codec: MsgpackCodec},
 	}
 }
 
-// CacheMiddleware creates a new cache middleware
+// CacheMiddleware creates a new cache middleware. Responses are buffered so
+// a strong ETag can be computed from the body before anything is sent, and
+// requests whose If-None-Match or If-Modified-Since validators still match
+// get a 304 without a body, whether the response came from cache or not.
 func CacheMiddleware(opts *CacheMiddlewareOptions) gin.HandlerFunc {
 	if opts == nil {
 		opts = DefaultCacheMiddlewareOptions(nil)
 	}
 
+	if opts.IncludeHeaders == nil {
+		opts.IncludeHeaders = DefaultCacheMiddlewareOptions(nil).IncludeHeaders
+	}
+
+	if opts.Serializer == nil {
+		opts.Serializer = DefaultCacheMiddlewareOptions(nil).Serializer
+	}
+
 	if opts.KeyGenerator == nil {
-		opts.KeyGenerator = defaultKeyGenerator
+		opts.KeyGenerator = headerKeyGenerator(opts.IncludeHeaders)
 	}
 
 	if opts.SkipCache == nil {
@@ -64,27 +79,29 @@ func CacheMiddleware(opts *CacheMiddlewareOptions) gin.HandlerFunc {
 		// Try to get from cache
 		if cachedResponse, err := opts.Cache.Get(c.Request.Context(), cacheKey); err == nil {
 			var response cachedHTTPResponse
-			if err := json.Unmarshal(cachedResponse, &response); err == nil {
-				// Set headers
+			if err := opts.Serializer.Unmarshal(cachedResponse, &response); err == nil {
+				// Headers set by outer middleware for this request, such as
+				// CORS and the CSP nonce, win over stored ones
+				header := c.Writer.Header()
 				for key, values := range response.Headers {
-					for _, value := range values {
-						c.Header(key, value)
+					if _, exists := header[key]; !exists {
+						header[key] = values
 					}
 				}
+				for _, name := range opts.IncludeHeaders {
+					addVary(header, name)
+				}
+				header.Set("X-Cache", "HIT")
 
-				// Set status code
-				c.Status(response.StatusCode)
-
-				// Write response body
-				c.Data(response.StatusCode, response.ContentType, response.Body)
-
-				// Add cache hit header
-				c.Header("X-Cache", "HIT")
+				writeCachedResponse(c, response)
+				c.Abort()
 				return
 			}
 		}
 
-		// Create a custom response writer to capture the response
+		before := c.Writer.Header().Clone()
+
+		// Create a custom response writer to buffer the response
 		responseWriter := &responseWriter{
 			ResponseWriter: c.Writer,
 			body:           &bytes.Buffer{},
@@ -94,80 +111,237 @@ func CacheMiddleware(opts *CacheMiddlewareOptions) gin.HandlerFunc {
 		// Process request
 		c.Next()
 
-		// Cache the response if it's successful
-		if c.Writer.Status() == http.StatusOK && len(responseWriter.body.Bytes()) > 0 {
-			response := cachedHTTPResponse{
-				StatusCode:  c.Writer.Status(),
-				ContentType: c.Writer.Header().Get("Content-Type"),
-				Body:        responseWriter.body.Bytes(),
-				Headers:     make(map[string][]string),
-			}
+		c.Writer = responseWriter.ResponseWriter
+		if responseWriter.streamed {
+			return
+		}
 
-			// Copy headers
-			for key, values := range c.Writer.Header() {
-				if !isExcludedHeader(key, opts.ExcludeHeaders) {
-					response.Headers[key] = values
-				}
-			}
+		header := c.Writer.Header()
+		header.Set("X-Cache", "MISS")
 
-			// Serialize and cache
-			if data, err := json.Marshal(response); err == nil {
-				opts.Cache.Set(c.Request.Context(), cacheKey, data, opts.DefaultTTL)
-			}
+		// Only complete 200 responses get validators and are cached
+		status := responseWriter.Status()
+		if status != http.StatusOK || responseWriter.body.Len() == 0 {
+			responseWriter.flush()
+			return
+		}
+
+		for _, name := range opts.IncludeHeaders {
+			addVary(header, name)
+		}
+
+		if header.Get("ETag") == "" {
+			header.Set("ETag", computeETag(responseWriter.body.Bytes()))
+		}
+
+		response := cachedHTTPResponse{
+			StatusCode:   status,
+			ContentType:  header.Get("Content-Type"),
+			Body:         responseWriter.body.Bytes(),
+			Headers:      handlerHeaders(before, header, opts.ExcludeHeaders),
+			ETag:         header.Get("ETag"),
+			LastModified: header.Get("Last-Modified"),
 		}
 
-		// Add cache miss header
-		c.Header("X-Cache", "MISS")
+		// Serialize and cache under any tags the handler added
+		if data, err := opts.Serializer.Marshal(response); err == nil {
+			setTagged(c.Request.Context(), opts.Cache, cacheKey, data, opts.DefaultTTL, ResponseTags(c))
+		}
+
+		writeCachedResponse(c, response)
 	}
 }
 
 // cachedHTTPResponse represents a cached HTTP response
 type cachedHTTPResponse struct {
-	StatusCode  int                 `json:"status_code"`
-	ContentType string              `json:"content_type"`
-	Body        []byte              `json:"body"`
-	Headers     map[string][]string `json:"headers"`
+	StatusCode   int                 `json:"status_code"`
+	ContentType  string              `json:"content_type"`
+	Body         []byte              `json:"body"`
+	Headers      map[string][]string `json:"headers"`
+	ETag         string              `json:"etag,omitempty"`
+	LastModified string              `json:"last_modified,omitempty"`
+}
+
+// writeCachedResponse sends the response, or a bodiless 304 when the request
+// validators match it
+func writeCachedResponse(c *gin.Context, response cachedHTTPResponse) {
+	if notModified(c.Request, response.ETag, response.LastModified) {
+		header := c.Writer.Header()
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+
+	c.Data(response.StatusCode, response.ContentType, response.Body)
+}
+
+// notModified evaluates If-None-Match, falling back to If-Modified-Since only
+// when no entity tags were sent, as RFC 9110 requires
+func notModified(r *http.Request, etag, lastModified string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etag == "" {
+			return false
+		}
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || weakETag(candidate) == weakETag(etag) {
+				return true
+			}
+		}
+		return false
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified == "" {
+		return false
+	}
+
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// weakETag strips the weak prefix so tags compare weakly; compression turns
+// strong tags into weak ones on the way out
+func weakETag(tag string) string {
+	return strings.TrimPrefix(tag, "W/")
 }
 
-// responseWriter captures the response for caching
+// computeETag builds a strong entity tag from the response body
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// addVary appends a header name to Vary unless it is already listed
+func addVary(header http.Header, name string) {
+	for _, existing := range header.Values("Vary") {
+		for _, token := range strings.Split(existing, ",") {
+			token = strings.TrimSpace(token)
+			if token == "*" || strings.EqualFold(token, name) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", name)
+}
+
+// responseWriter buffers the response so validators can be set before it is
+// sent. A handler that flushes switches it to pass-through and the response
+// is not cached.
 type responseWriter struct {
 	gin.ResponseWriter
-	body *bytes.Buffer
+	body     *bytes.Buffer
+	status   int
+	streamed bool
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.streamed {
+		rw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	rw.status = code
+}
+
+func (rw *responseWriter) WriteHeaderNow() {
+	if rw.streamed {
+		rw.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (rw *responseWriter) Status() int {
+	if rw.streamed || rw.status == 0 {
+		return rw.ResponseWriter.Status()
+	}
+	return rw.status
+}
+
+func (rw *responseWriter) Written() bool {
+	return rw.status != 0 || rw.body.Len() > 0 || rw.ResponseWriter.Written()
+}
+
+func (rw *responseWriter) Size() int {
+	if rw.streamed {
+		return rw.ResponseWriter.Size()
+	}
+	return rw.body.Len()
 }
 
 func (rw *responseWriter) Write(b []byte) (int, error) {
-	rw.body.Write(b)
-	return rw.ResponseWriter.Write(b)
+	if rw.streamed {
+		return rw.ResponseWriter.Write(b)
+	}
+	return rw.body.Write(b)
 }
 
-// defaultKeyGenerator generates a cache key based on request details
-func defaultKeyGenerator(c *gin.Context) string {
-	// Create a hash of the request
-	hash := md5.New()
+func (rw *responseWriter) WriteString(s string) (int, error) {
+	return rw.Write([]byte(s))
+}
 
-	// Include method and path
-	hash.Write([]byte(c.Request.Method + ":" + c.Request.URL.Path))
+func (rw *responseWriter) Flush() {
+	rw.flush()
+	rw.ResponseWriter.Flush()
+}
 
-	// Include query parameters
-	if c.Request.URL.RawQuery != "" {
-		hash.Write([]byte("?" + c.Request.URL.RawQuery))
+// flush sends whatever has been buffered and stops buffering
+func (rw *responseWriter) flush() {
+	if rw.streamed {
+		return
 	}
+	rw.streamed = true
 
-	// Include relevant headers
-	for _, header := range []string{"Accept", "Accept-Language", "User-Agent"} {
-		if value := c.GetHeader(header); value != "" {
-			hash.Write([]byte(":" + header + ":" + value))
-		}
+	if rw.status != 0 {
+		rw.ResponseWriter.WriteHeader(rw.status)
+	}
+	if rw.body.Len() > 0 {
+		rw.ResponseWriter.Write(rw.body.Bytes())
 	}
+}
+
+// headerKeyGenerator generates cache keys from the request method, path,
+// query, user and the headers the response varies on
+func headerKeyGenerator(includeHeaders []string) func(c *gin.Context) string {
+	return func(c *gin.Context) string {
+		// Create a hash of the request
+		hash := sha256.New()
+
+		// Include method and path
+		hash.Write([]byte(c.Request.Method + ":" + c.Request.URL.Path))
 
-	// Include user ID if available
-	if userID, exists := c.Get("user_id"); exists {
-		if id, ok := userID.(string); ok {
-			hash.Write([]byte(":user:" + id))
+		// Include query parameters
+		if c.Request.URL.RawQuery != "" {
+			hash.Write([]byte("?" + c.Request.URL.RawQuery))
 		}
-	}
 
-	return "http:" + hex.EncodeToString(hash.Sum(nil))
+		// Include the headers listed in Vary
+		for _, header := range includeHeaders {
+			if value := c.GetHeader(header); value != "" {
+				hash.Write([]byte(":" + header + ":" + value))
+			}
+		}
+
+		// Include user ID if available
+		if userID, exists := c.Get("user_id"); exists {
+			if id, ok := userID.(string); ok {
+				hash.Write([]byte(":user:" + id))
+			}
+		}
+
+		return "http:" + hex.EncodeToString(hash.Sum(nil))
+	}
 }
 
 // defaultSkipCache determines if caching should be skipped
@@ -190,6 +364,28 @@ func defaultSkipCache(c *gin.Context) bool {
 	return false
 }
 
+// handlerHeaders returns the response headers the handler set, which are
+// the ones worth storing. Headers from outer middleware are set again on
+// every request, Vary is rebuilt on a hit, and cookies and CORS headers are
+// specific to the client that caused the miss.
+func handlerHeaders(before, after http.Header, excludedHeaders []string) map[string][]string {
+	headers := make(map[string][]string)
+	for key, values := range after {
+		switch {
+		case key == "X-Cache", key == "Vary", key == "Set-Cookie":
+			continue
+		case strings.HasPrefix(key, "Access-Control-"):
+			continue
+		case isExcludedHeader(key, excludedHeaders):
+			continue
+		case slices.Equal(before[key], values):
+			continue
+		}
+		headers[key] = slices.Clone(values)
+	}
+	return headers
+}
+
 // isExcludedHeader checks if a header should be excluded from caching
 func isExcludedHeader(header string, excludedHeaders []string) bool {
 	headerLower := strings.ToLower(header)
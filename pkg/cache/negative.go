@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"xanny-go/pkg/exceptions"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound reports that a value is known not to exist. Loaders passed to
+// GetOrSet and GetOrSetJSON return it, or any error IsNotFound accepts, to
+// have the absence cached for NegativeTTL; the helper then returns it for
+// cached absences without calling the loader. It is distinct from
+// ErrKeyNotFound, which only means the cache has no entry.
+var ErrNotFound = errors.New("not found")
+
+// negativeEntry is stored in place of a value that does not exist. It cannot
+// start JSON, a serializer header or a refresh envelope
+var negativeEntry = []byte("\xC2notfound")
+
+// IsNotFound reports whether err means the value does not exist: it wraps
+// ErrNotFound or gorm.ErrRecordNotFound, or is a 404 exception such as the
+// one ParseGormError returns for a missing record
+func IsNotFound(err error) bool {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, gorm.ErrRecordNotFound) {
+		return true
+	}
+
+	var exception *exceptions.Exception
+	return errors.As(err, &exception) && exception != nil && exception.Status == http.StatusNotFound
+}
+
+// isNegativeEntry reports whether a stored value records an absence
+func isNegativeEntry(data []byte) bool {
+	return bytes.Equal(data, negativeEntry)
+}
+
+// SetNotFound records that key does not exist for NegativeTTL, under tags so
+// that invalidating them clears it along with positive entries
+func (ch *CacheHelper) SetNotFound(ctx context.Context, key string, tags ...string) error {
+	if ch.opts.NegativeTTL <= 0 {
+		return nil
+	}
+	return setTagged(ctx, ch.cache, key, negativeEntry, ch.opts.NegativeTTL, tags)
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// prometheusContentType is the Prometheus text exposition format
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// labelEscaper escapes label values for the text exposition format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WritePrometheus writes the metrics in the Prometheus text format, labeled
+// with each cache's name
+func WritePrometheus(w io.Writer, metrics ...*CacheMetrics) error {
+	bw := bufio.NewWriter(w)
+
+	family := func(name, kind, help string) {
+		bw.WriteString("# HELP " + name + " " + help + "\n")
+		bw.WriteString("# TYPE " + name + " " + kind + "\n")
+	}
+	sample := func(name, labels string, value string) {
+		bw.WriteString(name + "{" + labels + "} " + value + "\n")
+	}
+	cacheLabel := func(m *CacheMetrics) string {
+		return `cache="` + labelEscaper.Replace(m.name) + `"`
+	}
+	formatUint := func(v uint64) string {
+		return strconv.FormatUint(v, 10)
+	}
+
+	family("cache_requests_total", "counter", "Cache lookups by result.")
+	for _, m := range metrics {
+		sample("cache_requests_total", cacheLabel(m)+`,result="hit"`, formatUint(m.hits.Load()))
+		sample("cache_requests_total", cacheLabel(m)+`,result="miss"`, formatUint(m.misses.Load()))
+	}
+
+	family("cache_sets_total", "counter", "Values written to the cache.")
+	for _, m := range metrics {
+		sample("cache_sets_total", cacheLabel(m), formatUint(m.sets.Load()))
+	}
+
+	family("cache_deletes_total", "counter", "Keys removed from the cache by deletes and invalidations.")
+	for _, m := range metrics {
+		sample("cache_deletes_total", cacheLabel(m), formatUint(m.deletes.Load()))
+	}
+
+	family("cache_evictions_total", "counter", "Entries evicted to stay within size limits.")
+	for _, m := range metrics {
+		sample("cache_evictions_total", cacheLabel(m), formatUint(m.Evictions()))
+	}
+
+	family("cache_errors_total", "counter", "Failed cache operations, not counting misses.")
+	for _, m := range metrics {
+		for _, op := range cacheOperations {
+			sample("cache_errors_total", cacheLabel(m)+`,operation="`+op+`"`, formatUint(m.operations[op].errors.Load()))
+		}
+	}
+
+	family("cache_operation_duration_seconds", "histogram", "Cache operation latency.")
+	for _, m := range metrics {
+		for _, op := range cacheOperations {
+			histogram := &m.operations[op].latency
+			labels := cacheLabel(m) + `,operation="` + op + `"`
+
+			counts := histogram.cumulative()
+			for i, bound := range latencyBuckets {
+				sample("cache_operation_duration_seconds_bucket", labels+`,le="`+strconv.FormatFloat(bound, 'g', -1, 64)+`"`, formatUint(counts[i]))
+			}
+			sample("cache_operation_duration_seconds_bucket", labels+`,le="+Inf"`, formatUint(counts[len(counts)-1]))
+			sample("cache_operation_duration_seconds_sum", labels, strconv.FormatFloat(float64(histogram.sum.Load())/1e9, 'g', -1, 64))
+			sample("cache_operation_duration_seconds_count", labels, formatUint(counts[len(counts)-1]))
+		}
+	}
+
+	return bw.Flush()
+}
+
+// MetricsHandler serves the metrics of every cache registered with the
+// manager in the Prometheus text format
+func MetricsHandler(cm *CacheManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Content-Type", prometheusContentType)
+		c.Status(http.StatusOK)
+		WritePrometheus(c.Writer, cm.Metrics()...)
+	}
+}
@@ -0,0 +1,277 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+)
+
+// queryCacheSetting is the gorm setting WithQueryCache stores
+const queryCacheSetting = "cache:query"
+
+// queryCacheKeyPrefix starts every cached query key, followed by the table
+const queryCacheKeyPrefix = "gorm:"
+
+// queryCacheRawTable stands in for the table of raw queries
+const queryCacheRawTable = "raw"
+
+// rawWritePattern finds the table a raw INSERT, UPDATE, DELETE, TRUNCATE or
+// MERGE statement writes to
+var rawWritePattern = regexp.MustCompile(`(?is)^\s*(?:insert\s+into|update(?:\s+only)?|delete\s+from(?:\s+only)?|truncate(?:\s+table)?(?:\s+only)?|merge\s+into)\s+((?:"?\w+"?\.)?"?\w+"?)`)
+
+// rawReadPattern matches raw statements that never write
+var rawReadPattern = regexp.MustCompile(`(?is)^\s*(?:select|show|explain)\b`)
+
+// queryCacheOptions is what a query opted into caching with
+type queryCacheOptions struct {
+	ttl  time.Duration
+	tags []string
+}
+
+// queryCacheEntry is a cached query result
+type queryCacheEntry struct {
+	RowsAffected int64
+	Dest         []byte
+}
+
+// WithQueryCache returns a session whose queries are served from the cache
+// for ttl and stored under tags, besides the table's own TableTag. It has no
+// effect unless QueryCachePlugin is registered, and queries inside a
+// transaction always go to the database.
+//
+//	err := cache.WithQueryCache(tx, time.Minute).Where("uuid = ?", uuid).First(&user).Error
+func WithQueryCache(db *gorm.DB, ttl time.Duration, tags ...string) *gorm.DB {
+	return db.Set(queryCacheSetting, queryCacheOptions{ttl: ttl, tags: tags})
+}
+
+// TableTag is the tag every cached query on table carries. Add it for joined
+// tables so writes to them also invalidate the query
+func TableTag(table string) string {
+	return queryCacheKeyPrefix + "table:" + table
+}
+
+// QueryCacheOptions configures a QueryCachePlugin
+type QueryCacheOptions struct {
+	// Serializer encodes query results. Gob is the default because it keeps
+	// fields hidden from JSON, so Omit credential columns such as password
+	// hashes from cached queries
+	Serializer *Serializer
+
+	// NegativeTTL caches gorm.ErrRecordNotFound from First, Take and Last for
+	// this long, so repeated lookups of missing rows skip the database; zero
+	// disables it. Writes to the table clear these entries like any other
+	NegativeTTL time.Duration
+}
+
+// QueryCachePlugin is a gorm plugin that serves queries opted in with
+// WithQueryCache from a Cache. Entries are keyed by table and a hash of the
+// SQL with its vars, and creates, updates, deletes and raw statements on a
+// table invalidate every cached query on it. Raw writes are matched to their
+// table by parsing the statement; one that cannot be parsed clears every
+// cached query. Cached raw queries are cleared by any write, since their
+// tables are unknown. Invalidation happens when the statement runs, not when
+// its transaction commits, so keep TTLs short for tables written inside long
+// transactions.
+type QueryCachePlugin struct {
+	cache       Cache
+	serializer  *Serializer
+	negativeTTL time.Duration
+	query       func(*gorm.DB)
+}
+
+// NewQueryCachePlugin creates a query cache plugin backed by cache
+func NewQueryCachePlugin(cache Cache, opts *QueryCacheOptions) *QueryCachePlugin {
+	if opts == nil {
+		opts = &QueryCacheOptions{}
+	}
+
+	serializer := opts.Serializer
+	if serializer == nil {
+		serializer = &Serializer{Codec: GobCodec}
+	}
+
+	return &QueryCachePlugin{
+		cache:       cache,
+		serializer:  serializer,
+		negativeTTL: opts.NegativeTTL,
+	}
+}
+
+// Name implements gorm.Plugin
+func (p *QueryCachePlugin) Name() string {
+	return "cache:query"
+}
+
+// Initialize implements gorm.Plugin by wrapping the gorm:query callback and
+// registering invalidation after every write
+func (p *QueryCachePlugin) Initialize(db *gorm.DB) error {
+	p.query = db.Callback().Query().Get("gorm:query")
+	if p.query == nil {
+		return fmt.Errorf("query cache: gorm:query callback not found")
+	}
+
+	if err := db.Callback().Query().Replace("gorm:query", p.queryCallback); err != nil {
+		return err
+	}
+	if err := db.Callback().Create().After("gorm:create").Register("cache:invalidate", p.invalidate); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("cache:invalidate", p.invalidate); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After("gorm:delete").Register("cache:invalidate", p.invalidate); err != nil {
+		return err
+	}
+	return db.Callback().Raw().After("gorm:raw").Register("cache:invalidate", p.invalidate)
+}
+
+// queryCallback serves opted-in queries from the cache, running and storing
+// them on a miss
+func (p *QueryCachePlugin) queryCallback(db *gorm.DB) {
+	value, ok := db.Get(queryCacheSetting)
+	opts, _ := value.(queryCacheOptions)
+	if !ok || db.Error != nil || db.DryRun || inTransaction(db) {
+		p.query(db)
+		return
+	}
+
+	callbacks.BuildQuerySQL(db)
+	if db.Error != nil {
+		return
+	}
+
+	ctx := db.Statement.Context
+	table := queryTable(db)
+	key := p.key(db, table)
+
+	var tags []string
+	if _, ok := AsTaggedCache(p.cache); ok {
+		tags = append([]string{TableTag(table)}, opts.tags...)
+	}
+
+	if data, err := p.cache.Get(ctx, key); err == nil {
+		if isNegativeEntry(data) {
+			db.AddError(gorm.ErrRecordNotFound)
+			return
+		}
+
+		var entry queryCacheEntry
+		if err := p.serializer.Unmarshal(data, &entry); err == nil {
+			if err := p.serializer.Unmarshal(entry.Dest, db.Statement.Dest); err == nil {
+				db.RowsAffected = entry.RowsAffected
+				return
+			}
+		}
+	}
+
+	p.query(db)
+	if errors.Is(db.Error, gorm.ErrRecordNotFound) && p.negativeTTL > 0 {
+		ttl := p.negativeTTL
+		if opts.ttl > 0 && opts.ttl < ttl {
+			ttl = opts.ttl
+		}
+		if err := setTagged(ctx, p.cache, key, negativeEntry, ttl, tags); err != nil {
+			db.Logger.Warn(ctx, "query cache: cannot store %s: %v", key, err)
+		}
+		return
+	}
+	if db.Error != nil {
+		return
+	}
+
+	dest, err := p.serializer.Marshal(db.Statement.Dest)
+	if err != nil {
+		db.Logger.Warn(ctx, "query cache: cannot encode %T: %v", db.Statement.Dest, err)
+		return
+	}
+
+	data, err := p.serializer.Marshal(queryCacheEntry{RowsAffected: db.RowsAffected, Dest: dest})
+	if err != nil {
+		return
+	}
+
+	if err := setTagged(ctx, p.cache, key, data, opts.ttl, tags); err != nil {
+		db.Logger.Warn(ctx, "query cache: cannot store %s: %v", key, err)
+	}
+}
+
+// invalidate drops every cached query on the statement's table, and every
+// cached raw query, after a write
+func (p *QueryCachePlugin) invalidate(db *gorm.DB) {
+	if db.Error != nil || db.DryRun {
+		return
+	}
+
+	ctx := context.WithoutCancel(db.Statement.Context)
+	table := db.Statement.Table
+
+	if table == "" {
+		sql := db.Statement.SQL.String()
+		if rawReadPattern.MatchString(sql) {
+			return
+		}
+
+		match := rawWritePattern.FindStringSubmatch(sql)
+		if match == nil {
+			if err := p.cache.InvalidatePattern(ctx, queryCacheKeyPrefix+"*"); err != nil {
+				db.Logger.Error(ctx, "query cache: cannot invalidate after raw statement: %v", err)
+			}
+			return
+		}
+		table = rawTableName(match[1])
+	}
+
+	var err error
+	if tagged, ok := AsTaggedCache(p.cache); ok {
+		_, err = tagged.InvalidateTags(ctx, TableTag(table), TableTag(queryCacheRawTable))
+	} else {
+		err = errors.Join(
+			p.cache.InvalidatePattern(ctx, queryCacheKeyPrefix+EscapePattern(table)+":*"),
+			p.cache.InvalidatePattern(ctx, queryCacheKeyPrefix+queryCacheRawTable+":*"),
+		)
+	}
+
+	if err != nil {
+		db.Logger.Error(ctx, "query cache: cannot invalidate %s: %v", table, err)
+	}
+}
+
+// rawTableName turns a table reference from raw SQL into the name gorm uses,
+// dropping quotes and any schema
+func rawTableName(name string) string {
+	name = strings.Trim(name, `"`)
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = strings.Trim(name[i+1:], `"`)
+	}
+	return name
+}
+
+// key is the cache key for the statement's SQL with its vars inlined
+func (p *QueryCachePlugin) key(db *gorm.DB, table string) string {
+	sql := strings.Join(strings.Fields(db.Dialector.Explain(db.Statement.SQL.String(), db.Statement.Vars...)), " ")
+	sum := sha256.Sum256([]byte(sql))
+	return queryCacheKeyPrefix + table + ":" + hex.EncodeToString(sum[:])
+}
+
+// queryTable is the table a query reads, or a placeholder for raw SQL
+func queryTable(db *gorm.DB) string {
+	if db.Statement.Table != "" {
+		return db.Statement.Table
+	}
+	return queryCacheRawTable
+}
+
+// inTransaction reports whether the statement runs inside a transaction,
+// where results may include uncommitted writes
+func inTransaction(db *gorm.DB) bool {
+	_, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
@@ -3,19 +3,30 @@ package cache
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
-// RedisCache implements the Cache interface using Redis
+// redisScanCount is the COUNT hint for each SCAN call and so roughly the
+// number of keys unlinked per pipeline
+const redisScanCount = 500
+
+// RedisCache implements the Cache interface using Redis. Any go-redis client
+// works, including cluster and sentinel-backed clients
 type RedisCache struct {
-	client *redis.Client
+	client redis.UniversalClient
 	opts   *CacheOptions
+
+	// ownsClient is set when the cache created its client and so closes it
+	ownsClient bool
 }
 
-// NewRedisCache creates a new Redis cache instance
-func NewRedisCache(client *redis.Client, opts *CacheOptions) *RedisCache {
+// NewRedisCache creates a new Redis cache instance on client. The caller
+// keeps ownership of the client: Close leaves it open
+func NewRedisCache(client redis.UniversalClient, opts *CacheOptions) *RedisCache {
 	if opts == nil {
 		opts = DefaultCacheOptions()
 	}
@@ -32,13 +43,35 @@ func (rc *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
 	result, err := rc.client.Get(ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
-			return nil, fmt.Errorf("key not found: %s", key)
+			return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, key)
 		}
 		return nil, err
 	}
 	return []byte(result), nil
 }
 
+// getWithTTL retrieves a value with its remaining TTL, which is negative
+// when the key never expires
+func (rc *RedisCache) getWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	key = rc.opts.Prefix + key
+
+	pipe := rc.client.Pipeline()
+	get := pipe.Get(ctx, key)
+	pttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, 0, err
+	}
+
+	result, err := get.Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, 0, fmt.Errorf("%w: %s", ErrKeyNotFound, key)
+		}
+		return nil, 0, err
+	}
+	return []byte(result), pttl.Val(), nil
+}
+
 // Set stores a value in Redis cache with expiration
 func (rc *RedisCache) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
 	key = rc.opts.Prefix + key
@@ -48,6 +81,101 @@ func (rc *RedisCache) Set(ctx context.Context, key string, value []byte, expirat
 	return rc.client.Set(ctx, key, value, expiration).Err()
 }
 
+// SetWithTags stores a value and adds its key to a Redis set per tag. A tag
+// set lives at least as long as its longest-lived entry; stale members left
+// behind by expired entries are harmless and go when the tag is invalidated.
+func (rc *RedisCache) SetWithTags(ctx context.Context, key string, value []byte, expiration time.Duration, tags ...string) error {
+	key = rc.opts.Prefix + key
+	if expiration == 0 {
+		expiration = rc.opts.DefaultTTL
+	}
+
+	pipe := rc.client.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(tags))
+	for i, tag := range tags {
+		ttls[i] = pipe.PTTL(ctx, rc.tagKey(tag))
+	}
+	pipe.Set(ctx, key, value, expiration)
+	for _, tag := range tags {
+		pipe.SAdd(ctx, rc.tagKey(tag), key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	// PTTL was read before SADD: -2 means the set is new, -1 that it never
+	// expires
+	pipe = rc.client.Pipeline()
+	for i, tag := range tags {
+		ttl := ttls[i].Val()
+		switch {
+		case expiration <= 0:
+			pipe.Persist(ctx, rc.tagKey(tag))
+		case ttl == -2, ttl >= 0 && ttl < expiration:
+			pipe.PExpire(ctx, rc.tagKey(tag), expiration)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// InvalidateTags removes every entry carrying any of the tags and returns the
+// number of entries removed. Members are taken off each tag set with SPOP so
+// entries tagged while the invalidation runs are either removed or keep their
+// tag.
+func (rc *RedisCache) InvalidateTags(ctx context.Context, tags ...string) (int64, error) {
+	deleted, _, err := rc.invalidateTags(ctx, tags)
+	return deleted, err
+}
+
+// invalidateTags is InvalidateTags that also returns the keys taken off the
+// tag sets, without the prefix
+func (rc *RedisCache) invalidateTags(ctx context.Context, tags []string) (int64, []string, error) {
+	var (
+		deleted int64
+		removed []string
+	)
+
+	for _, tag := range tags {
+		tagKey := rc.tagKey(tag)
+		for {
+			if err := ctx.Err(); err != nil {
+				return deleted, removed, err
+			}
+
+			keys, err := rc.client.SPopN(ctx, tagKey, redisScanCount).Result()
+			if err != nil && err != redis.Nil {
+				return deleted, removed, err
+			}
+			if len(keys) == 0 {
+				break
+			}
+
+			pipe := rc.client.Pipeline()
+			cmds := make([]*redis.IntCmd, len(keys))
+			for i, key := range keys {
+				cmds[i] = pipe.Unlink(ctx, key)
+				removed = append(removed, strings.TrimPrefix(key, rc.opts.Prefix))
+			}
+
+			_, err = pipe.Exec(ctx)
+			for _, cmd := range cmds {
+				deleted += cmd.Val()
+			}
+			if err != nil {
+				return deleted, removed, err
+			}
+		}
+	}
+
+	return deleted, removed, nil
+}
+
+// tagKey is the Redis set holding the keys tagged with tag
+func (rc *RedisCache) tagKey(tag string) string {
+	return rc.opts.Prefix + tagKeyPrefix + tag
+}
+
 // Delete removes a value from Redis cache
 func (rc *RedisCache) Delete(ctx context.Context, key string) error {
 	key = rc.opts.Prefix + key
@@ -64,37 +192,86 @@ func (rc *RedisCache) Exists(ctx context.Context, key string) (bool, error) {
 	return result > 0, nil
 }
 
-// Flush removes all keys from Redis cache (use with caution!)
+// Flush removes all keys under the cache prefix (use with caution!)
 func (rc *RedisCache) Flush(ctx context.Context) error {
-	pattern := rc.opts.Prefix + "*"
-	keys, err := rc.client.Keys(ctx, pattern).Result()
-	if err != nil {
-		return err
-	}
-
-	if len(keys) > 0 {
-		return rc.client.Del(ctx, keys...).Err()
-	}
-	return nil
+	_, err := rc.DeletePattern(ctx, "*")
+	return err
 }
 
-// Close closes the Redis connection
+// Close closes the Redis connection if the cache created it
 func (rc *RedisCache) Close() error {
+	if !rc.ownsClient {
+		return nil
+	}
 	return rc.client.Close()
 }
 
-// InvalidatePattern removes all keys matching a pattern
+// InvalidatePattern removes all keys matching a glob pattern, see MatchPattern
 func (rc *RedisCache) InvalidatePattern(ctx context.Context, pattern string) error {
-	pattern = rc.opts.Prefix + pattern
-	keys, err := rc.client.Keys(ctx, pattern).Result()
-	if err != nil {
-		return err
+	_, err := rc.DeletePattern(ctx, pattern)
+	return err
+}
+
+// DeletePattern removes all keys matching a glob pattern and returns how many
+// were deleted. Keys are found with SCAN rather than KEYS so Redis is never
+// blocked, and each page is removed with pipelined UNLINKs. On a cluster every
+// master is scanned. Cancelling ctx stops the iteration; keys deleted up to
+// that point are still counted.
+func (rc *RedisCache) DeletePattern(ctx context.Context, pattern string) (int64, error) {
+	match := EscapePattern(rc.opts.Prefix) + pattern
+
+	cluster, ok := rc.client.(*redis.ClusterClient)
+	if !ok {
+		return scanDelete(ctx, rc.client, match)
 	}
 
-	if len(keys) > 0 {
-		return rc.client.Del(ctx, keys...).Err()
+	var deleted atomic.Int64
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, master *redis.Client) error {
+		n, err := scanDelete(ctx, master, match)
+		deleted.Add(n)
+		return err
+	})
+	return deleted.Load(), err
+}
+
+// scanDelete unlinks every key on a single node that matches match
+func scanDelete(ctx context.Context, client redis.Cmdable, match string) (int64, error) {
+	var cursor uint64
+	var deleted int64
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+
+		keys, next, err := client.Scan(ctx, cursor, match, redisScanCount).Result()
+		if err != nil {
+			return deleted, err
+		}
+
+		if len(keys) > 0 {
+			// One UNLINK per key: a multi-key UNLINK fails with CROSSSLOT on
+			// cluster nodes
+			pipe := client.Pipeline()
+			cmds := make([]*redis.IntCmd, len(keys))
+			for i, key := range keys {
+				cmds[i] = pipe.Unlink(ctx, key)
+			}
+
+			_, err := pipe.Exec(ctx)
+			for _, cmd := range cmds {
+				deleted += cmd.Val()
+			}
+			if err != nil {
+				return deleted, err
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return deleted, nil
+		}
 	}
-	return nil
 }
 
 // GetTTL returns the remaining TTL for a key
@@ -112,6 +289,15 @@ func (rc *RedisCache) SetNX(ctx context.Context, key string, value []byte, expir
 	return rc.client.SetNX(ctx, key, value, expiration).Result()
 }
 
+// SetXX sets a value only if the key exists
+func (rc *RedisCache) SetXX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	key = rc.opts.Prefix + key
+	if expiration == 0 {
+		expiration = rc.opts.DefaultTTL
+	}
+	return rc.client.SetXX(ctx, key, value, expiration).Result()
+}
+
 // Increment increments a numeric value
 func (rc *RedisCache) Increment(ctx context.Context, key string, value int64) (int64, error) {
 	key = rc.opts.Prefix + key
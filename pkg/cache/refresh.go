@@ -0,0 +1,268 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+	"xanny-go/pkg/logger"
+)
+
+// refreshEnvelopeMagic marks values written with soft-TTL metadata
+const refreshEnvelopeMagic = 0xC0
+
+// refreshEnvelopeHeader is the magic byte, the fresh-until time in unix
+// nanoseconds and the recompute duration in microseconds
+const refreshEnvelopeHeader = 1 + 8 + 4
+
+// lockPollInterval is how often callers waiting on another replica's lock
+// check whether the value has arrived
+const lockPollInterval = 50 * time.Millisecond
+
+// defaultLoadTimeout bounds a shared load when RefreshOptions.LoadTimeout is
+// not set
+const defaultLoadTimeout = 30 * time.Second
+
+// AtomicSetter is implemented by caches that can set a key only if it is
+// absent, which is enough for a simple distributed lock
+type AtomicSetter interface {
+	// SetNX stores a value only if the key does not exist and reports whether
+	// it was stored
+	SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error)
+}
+
+// RefreshOptions configures how GetOrSet and GetOrSetJSON recompute values.
+// Concurrent misses for a key within one process always share a single call
+// to fn, which runs detached from any one caller's cancellation; a caller
+// that gives up stops waiting without failing the others. The options below
+// are off when zero.
+type RefreshOptions struct {
+	// StaleTTL keeps a value for this long past its TTL. During that window
+	// callers get the stale value immediately while one of them recomputes it
+	// in the background.
+	StaleTTL time.Duration
+
+	// Beta enables probabilistic early expiration: a value is recomputed
+	// before its TTL with a probability that grows as expiry nears and with
+	// how long fn took last time. 1 is the usual choice; larger refreshes
+	// earlier.
+	Beta float64
+
+	// LockTTL takes a token-checked Locker lock before calling fn, so only
+	// one replica recomputes a key. Needs a memory, Redis or tiered cache.
+	LockTTL time.Duration
+
+	// LockWait is how long a caller that lost the lock waits for the winner's
+	// value before computing it itself. Defaults to LockTTL.
+	LockWait time.Duration
+
+	// LoadTimeout bounds a shared load, since no caller's context does.
+	// Defaults to 30 seconds.
+	LoadTimeout time.Duration
+}
+
+// WithRefreshOptions sets how GetOrSet and GetOrSetJSON recompute values and
+// returns the helper. Values written with StaleTTL or Beta set carry a small
+// header that the helper's Get methods strip, so read them through the helper.
+func (ch *CacheHelper) WithRefreshOptions(opts RefreshOptions) *CacheHelper {
+	if opts.LockWait <= 0 {
+		opts.LockWait = opts.LockTTL
+	}
+	ch.refresh = opts
+
+	ch.locker = nil
+	if opts.LockTTL > 0 {
+		if locker, err := NewLocker(ch.cache, nil); err == nil {
+			ch.locker = locker
+		}
+	}
+	return ch
+}
+
+// refreshEntry is a decoded cache value with its soft-TTL metadata
+type refreshEntry struct {
+	value      []byte
+	freshUntil time.Time
+	delta      time.Duration
+	enveloped  bool
+}
+
+// usesEnvelope reports whether values need soft-TTL metadata
+func (ch *CacheHelper) usesEnvelope() bool {
+	return ch.refresh.StaleTTL > 0 || ch.refresh.Beta > 0
+}
+
+// encodeRefreshEntry prefixes value with its fresh-until time and how long it
+// took to compute
+func encodeRefreshEntry(value []byte, freshUntil time.Time, delta time.Duration) []byte {
+	data := make([]byte, refreshEnvelopeHeader+len(value))
+	data[0] = refreshEnvelopeMagic
+	binary.BigEndian.PutUint64(data[1:9], uint64(freshUntil.UnixNano()))
+	binary.BigEndian.PutUint32(data[9:13], uint32(min(delta.Microseconds(), math.MaxUint32)))
+	copy(data[refreshEnvelopeHeader:], value)
+	return data
+}
+
+// decodeRefreshEntry splits the metadata off a value written by
+// encodeRefreshEntry; other values are returned as they are
+func decodeRefreshEntry(data []byte) refreshEntry {
+	if len(data) < refreshEnvelopeHeader || data[0] != refreshEnvelopeMagic {
+		return refreshEntry{value: data}
+	}
+
+	return refreshEntry{
+		value:      data[refreshEnvelopeHeader:],
+		freshUntil: time.Unix(0, int64(binary.BigEndian.Uint64(data[1:9]))),
+		delta:      time.Duration(binary.BigEndian.Uint32(data[9:13])) * time.Microsecond,
+		enveloped:  true,
+	}
+}
+
+// shouldRefresh reports whether an entry that is still in cache needs to be
+// recomputed: it is past its soft TTL, or probabilistic early expiration
+// (XFetch) fires
+func (ch *CacheHelper) shouldRefresh(entry refreshEntry, now time.Time) bool {
+	if !entry.enveloped {
+		return false
+	}
+	if !now.Before(entry.freshUntil) {
+		return true
+	}
+	if ch.refresh.Beta <= 0 || entry.delta <= 0 {
+		return false
+	}
+
+	early := time.Duration(float64(entry.delta) * ch.refresh.Beta * -math.Log(1-rand.Float64()))
+	return !now.Add(early).Before(entry.freshUntil)
+}
+
+// getOrLoad returns the cached value for key, loading it with fn on a miss.
+// Stale or early-expiring values are returned at once and refreshed in the
+// background.
+func (ch *CacheHelper) getOrLoad(ctx context.Context, key string, fn func() ([]byte, time.Duration, error), tags []string) ([]byte, error) {
+	if data, err := ch.cache.Get(ctx, key); err == nil {
+		if isNegativeEntry(data) {
+			return nil, ErrNotFound
+		}
+
+		entry := decodeRefreshEntry(data)
+		if ch.shouldRefresh(entry, time.Now()) {
+			ch.refreshInBackground(ctx, key, fn, tags)
+		}
+		return entry.value, nil
+	}
+
+	results := ch.group.DoChan(key, func() (interface{}, error) {
+		return ch.detachedLoad(ctx, key, fn, tags)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Val.([]byte), nil
+	}
+}
+
+// refreshInBackground recomputes key once per process, detached from the
+// caller's cancellation
+func (ch *CacheHelper) refreshInBackground(ctx context.Context, key string, fn func() ([]byte, time.Duration, error), tags []string) {
+	if _, running := ch.refreshing.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+
+	go func() {
+		defer ch.refreshing.Delete(key)
+		ch.group.Do(key, func() (interface{}, error) {
+			return ch.detachedLoad(ctx, key, fn, tags)
+		})
+	}()
+}
+
+// detachedLoad runs load without ctx's cancellation, bounded by LoadTimeout,
+// so the caller that started a shared load cannot cancel it for the others
+func (ch *CacheHelper) detachedLoad(ctx context.Context, key string, fn func() ([]byte, time.Duration, error), tags []string) ([]byte, error) {
+	timeout := ch.refresh.LoadTimeout
+	if timeout <= 0 {
+		timeout = defaultLoadTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	return ch.load(ctx, key, fn, tags)
+}
+
+// load computes and stores the value for key, holding the distributed lock
+// when one is configured. A caller that loses the lock waits for the winner's
+// value and computes it itself if none arrives in time.
+func (ch *CacheHelper) load(ctx context.Context, key string, fn func() ([]byte, time.Duration, error), tags []string) ([]byte, error) {
+	if ch.locker != nil {
+		lock, err := ch.locker.TryAcquire(ctx, key, ch.refresh.LockTTL)
+		if err == nil {
+			defer lock.Release(context.WithoutCancel(ctx))
+		} else if errors.Is(err, ErrLockNotAcquired) {
+			if value, ok := ch.waitForValue(ctx, key); ok {
+				if isNegativeEntry(value) {
+					return nil, ErrNotFound
+				}
+				return value, nil
+			}
+		}
+	}
+
+	start := time.Now()
+	value, expiration, err := fn()
+	if IsNotFound(err) && ch.opts.NegativeTTL > 0 {
+		ch.SetNotFound(ctx, key, tags...)
+		return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if expiration <= 0 {
+		expiration = ch.opts.DefaultTTL
+	}
+
+	data := value
+	if ch.usesEnvelope() {
+		data = encodeRefreshEntry(value, time.Now().Add(expiration), time.Since(start))
+		expiration += ch.refresh.StaleTTL
+	}
+
+	// The loaded value is still returned; callers only lose the cached copy
+	if err := setTagged(ctx, ch.cache, key, data, expiration, tags); err != nil {
+		logger.Warning("Cache write for %s failed: %v", key, err)
+	}
+
+	return value, nil
+}
+
+// waitForValue polls for a value another replica is computing
+func (ch *CacheHelper) waitForValue(ctx context.Context, key string) ([]byte, bool) {
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+
+	deadline := time.NewTimer(ch.refresh.LockWait)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-deadline.C:
+			return nil, false
+		case <-ticker.C:
+		}
+
+		if data, err := ch.cache.Get(ctx, key); err == nil {
+			return decodeRefreshEntry(data).value, true
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// snapshotVersion is bumped when the snapshot layout changes. Snapshots
+// from other versions are ignored
+const snapshotVersion = 1
+
+// snapshot is the on-disk form of a MemoryCache
+type snapshot struct {
+	Version int
+	Items   []snapshotItem
+}
+
+// snapshotItem is one entry, keyed without the cache prefix
+type snapshotItem struct {
+	Key        string
+	Value      []byte
+	Expiration time.Time
+	Tags       []string
+}
+
+// WriteSnapshot writes every live entry to w. Expiration times are absolute,
+// so restored entries keep their remaining TTL
+func (mc *MemoryCache) WriteSnapshot(w io.Writer) error {
+	now := time.Now()
+	snap := snapshot{Version: snapshotVersion}
+
+	mc.mu.Lock()
+	for _, item := range mc.items {
+		if item.expired(now) {
+			continue
+		}
+		snap.Items = append(snap.Items, snapshotItem{
+			Key:        strings.TrimPrefix(item.key, mc.opts.Prefix),
+			Value:      item.value,
+			Expiration: item.expiration,
+			Tags:       item.tags,
+		})
+	}
+	mc.mu.Unlock()
+
+	return gob.NewEncoder(w).Encode(snap)
+}
+
+// ReadSnapshot loads entries written by WriteSnapshot and returns how many
+// were restored. Expired entries and keys already in the cache are skipped,
+// and size limits apply as for any insert
+func (mc *MemoryCache) ReadSnapshot(r io.Reader) (int, error) {
+	var snap snapshot
+	if err := gob.NewDecoder(r).Decode(&snap); err != nil {
+		return 0, fmt.Errorf("decode cache snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return 0, nil
+	}
+
+	now := time.Now()
+	restored := 0
+
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	for _, entry := range snap.Items {
+		item := &cacheItem{
+			key:        mc.opts.Prefix + entry.Key,
+			value:      entry.Value,
+			expiration: entry.Expiration,
+			tags:       entry.Tags,
+		}
+		if item.expired(now) {
+			continue
+		}
+		if _, exists := mc.items[item.key]; exists {
+			continue
+		}
+		if mc.opts.MaxBytes > 0 && item.size() > mc.opts.MaxBytes {
+			continue
+		}
+
+		mc.insertLocked(item)
+		restored++
+	}
+
+	return restored, nil
+}
+
+// SaveSnapshot writes a snapshot to path, replacing any previous one
+// atomically
+func (mc *MemoryCache) SaveSnapshot(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create cache snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := mc.WriteSnapshot(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write cache snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write cache snapshot: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot restores a snapshot saved at path. A missing file restores
+// nothing and is not an error
+func (mc *MemoryCache) LoadSnapshot(path string) (int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("open cache snapshot: %w", err)
+	}
+	defer file.Close()
+
+	return mc.ReadSnapshot(file)
+}
+
+// EnableSnapshot restores the snapshot at path, if any, and saves a new one
+// there when the cache is closed. It returns how many entries were restored
+func (mc *MemoryCache) EnableSnapshot(path string) (int, error) {
+	restored, err := mc.LoadSnapshot(path)
+	if err != nil {
+		return 0, err
+	}
+
+	mc.mu.Lock()
+	mc.snapshotPath = path
+	mc.mu.Unlock()
+	return restored, nil
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tagKeyPrefix namespaces the tag index inside a cache's key space
+const tagKeyPrefix = "__tags:"
+
+// responseTagsKey is the gin context key holding tags for the cached response
+const responseTagsKey = "cache_tags"
+
+// ErrTagsNotSupported is returned when tags are used with a cache that does
+// not implement TaggedCache
+var ErrTagsNotSupported = errors.New("cache does not support tags")
+
+// TaggedCache is implemented by caches that can group entries under tags so
+// they can be invalidated together without knowing their keys
+type TaggedCache interface {
+	Cache
+
+	// SetWithTags stores a value and records it under each tag
+	SetWithTags(ctx context.Context, key string, value []byte, expiration time.Duration, tags ...string) error
+
+	// InvalidateTags removes every entry carrying any of the tags and returns
+	// the number of entries removed
+	InvalidateTags(ctx context.Context, tags ...string) (int64, error)
+}
+
+// AsTaggedCache returns cache as a TaggedCache if it supports tags. An
+// InstrumentedCache always has the tag methods, so it is judged by the cache
+// it wraps; use this instead of a type assertion.
+func AsTaggedCache(cache Cache) (TaggedCache, bool) {
+	if _, ok := unwrapCache(cache).(TaggedCache); !ok {
+		return nil, false
+	}
+	tagged, ok := cache.(TaggedCache)
+	return tagged, ok
+}
+
+// AddTags tags the response of the current request. When CacheMiddleware
+// stores the response it is recorded under these tags, so InvalidateTags can
+// evict it alongside the data it was built from.
+func AddTags(c *gin.Context, tags ...string) {
+	existing, _ := c.Get(responseTagsKey)
+	current, _ := existing.([]string)
+	c.Set(responseTagsKey, append(current, tags...))
+}
+
+// ResponseTags returns the tags added to the current response with AddTags
+func ResponseTags(c *gin.Context) []string {
+	tags, _ := c.Get(responseTagsKey)
+	current, _ := tags.([]string)
+	return current
+}
+
+// setTagged stores a value with tags when there are any, failing if the cache
+// cannot hold them
+func setTagged(ctx context.Context, cache Cache, key string, value []byte, expiration time.Duration, tags []string) error {
+	if len(tags) == 0 {
+		return cache.Set(ctx, key, value, expiration)
+	}
+
+	tagged, ok := AsTaggedCache(cache)
+	if !ok {
+		return ErrTagsNotSupported
+	}
+	return tagged.SetWithTags(ctx, key, value, expiration, tags...)
+}
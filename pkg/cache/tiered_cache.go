@@ -0,0 +1,320 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// tieredInvalidationChannel is the pub/sub channel, under the Redis prefix,
+// that replicas use to tell each other which L1 entries to drop
+const tieredInvalidationChannel = "__invalidate"
+
+// TieredOptions configures a TieredCache
+type TieredOptions struct {
+	// L1TTL is the longest an entry stays in the in-process tier. It bounds
+	// how stale a replica can get if an invalidation message is lost.
+	L1TTL time.Duration
+}
+
+// DefaultTieredOptions returns default tiered cache options
+func DefaultTieredOptions() *TieredOptions {
+	return &TieredOptions{
+		L1TTL: 30 * time.Second,
+	}
+}
+
+// tierStats counts lookups answered by one tier
+type tierStats struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// snapshot returns the counters with their hit ratio
+func (ts *tierStats) snapshot() map[string]interface{} {
+	hits, misses := ts.hits.Load(), ts.misses.Load()
+
+	ratio := 0.0
+	if total := hits + misses; total > 0 {
+		ratio = float64(hits) / float64(total)
+	}
+
+	return map[string]interface{}{
+		"hits":      hits,
+		"misses":    misses,
+		"hit_ratio": ratio,
+	}
+}
+
+// invalidation is the message replicas publish after a write
+type invalidation struct {
+	Origin   string   `json:"origin"`
+	Keys     []string `json:"keys,omitempty"`
+	Patterns []string `json:"patterns,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// TieredCache implements the Cache interface with a bounded in-process
+// MemoryCache (L1) in front of a RedisCache (L2). Reads fill L1 from L2 for
+// at most L1TTL; writes go to both tiers and are broadcast over Redis pub/sub
+// so every other replica drops its L1 copy.
+type TieredCache struct {
+	l1   *MemoryCache
+	l2   *RedisCache
+	opts *TieredOptions
+
+	origin  string
+	channel string
+	pubsub  *redis.PubSub
+
+	// generation changes whenever L1 is invalidated, so a read that raced an
+	// invalidation does not put the old L2 value back into L1
+	generation atomic.Uint64
+
+	l1Stats tierStats
+	l2Stats tierStats
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewTieredCache creates a two-tier cache and starts listening for
+// invalidations from other replicas
+func NewTieredCache(l1 *MemoryCache, l2 *RedisCache, opts *TieredOptions) *TieredCache {
+	if opts == nil {
+		opts = DefaultTieredOptions()
+	}
+
+	tc := &TieredCache{
+		l1:      l1,
+		l2:      l2,
+		opts:    opts,
+		origin:  uuid.NewString(),
+		channel: l2.opts.Prefix + tieredInvalidationChannel,
+		done:    make(chan struct{}),
+	}
+
+	tc.pubsub = l2.client.Subscribe(context.Background(), tc.channel)
+	go tc.listen()
+
+	return tc
+}
+
+// listen applies invalidations published by other replicas until Close
+func (tc *TieredCache) listen() {
+	messages := tc.pubsub.Channel()
+	for {
+		select {
+		case <-tc.done:
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			var inv invalidation
+			if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil || inv.Origin == tc.origin {
+				continue
+			}
+			tc.invalidateLocal(context.Background(), inv)
+		}
+	}
+}
+
+// invalidateLocal drops the L1 entries an invalidation covers
+func (tc *TieredCache) invalidateLocal(ctx context.Context, inv invalidation) {
+	tc.generation.Add(1)
+
+	for _, key := range inv.Keys {
+		tc.l1.Delete(ctx, key)
+	}
+	for _, pattern := range inv.Patterns {
+		tc.l1.DeletePattern(ctx, pattern)
+	}
+	if len(inv.Tags) > 0 {
+		tc.l1.InvalidateTags(ctx, inv.Tags...)
+	}
+}
+
+// invalidate drops the entries from this replica's L1 and tells the others to
+// do the same
+func (tc *TieredCache) invalidate(ctx context.Context, inv invalidation) error {
+	tc.invalidateLocal(ctx, inv)
+
+	inv.Origin = tc.origin
+	payload, err := json.Marshal(inv)
+	if err != nil {
+		return err
+	}
+	return tc.l2.client.Publish(ctx, tc.channel, payload).Err()
+}
+
+// l1Expiration caps an entry's L1 lifetime at L1TTL
+func (tc *TieredCache) l1Expiration(expiration time.Duration) time.Duration {
+	if expiration <= 0 || expiration > tc.opts.L1TTL {
+		return tc.opts.L1TTL
+	}
+	return expiration
+}
+
+// Get retrieves a value from L1, falling back to L2 and filling L1 on a hit.
+// The L1 copy never outlives the L2 entry.
+func (tc *TieredCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if value, err := tc.l1.Get(ctx, key); err == nil {
+		tc.l1Stats.hits.Add(1)
+		return value, nil
+	}
+	tc.l1Stats.misses.Add(1)
+
+	generation := tc.generation.Load()
+	value, ttl, err := tc.l2.getWithTTL(ctx, key)
+	if err != nil {
+		tc.l2Stats.misses.Add(1)
+		return nil, err
+	}
+	tc.l2Stats.hits.Add(1)
+
+	// A negative TTL means the L2 entry never expires
+	if ttl < 0 {
+		ttl = 0
+	}
+	if tc.generation.Load() == generation {
+		tc.l1.Set(ctx, key, value, tc.l1Expiration(ttl))
+	}
+	return value, nil
+}
+
+// Set stores a value in both tiers and invalidates it on other replicas
+func (tc *TieredCache) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	if err := tc.l2.Set(ctx, key, value, expiration); err != nil {
+		return err
+	}
+	if err := tc.invalidate(ctx, invalidation{Keys: []string{key}}); err != nil {
+		return err
+	}
+
+	return tc.l1.Set(ctx, key, value, tc.l1Expiration(expiration))
+}
+
+// SetNX stores a value in L2 only if the key does not exist there
+func (tc *TieredCache) SetNX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	stored, err := tc.l2.SetNX(ctx, key, value, expiration)
+	if err != nil || !stored {
+		return stored, err
+	}
+	return true, tc.invalidate(ctx, invalidation{Keys: []string{key}})
+}
+
+// SetXX stores a value in both tiers only if the key exists in L2
+func (tc *TieredCache) SetXX(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	stored, err := tc.l2.SetXX(ctx, key, value, expiration)
+	if err != nil || !stored {
+		return stored, err
+	}
+	if err := tc.invalidate(ctx, invalidation{Keys: []string{key}}); err != nil {
+		return true, err
+	}
+
+	return true, tc.l1.Set(ctx, key, value, tc.l1Expiration(expiration))
+}
+
+// SetWithTags stores a tagged value in both tiers and invalidates it on
+// other replicas
+func (tc *TieredCache) SetWithTags(ctx context.Context, key string, value []byte, expiration time.Duration, tags ...string) error {
+	if err := tc.l2.SetWithTags(ctx, key, value, expiration, tags...); err != nil {
+		return err
+	}
+	if err := tc.invalidate(ctx, invalidation{Keys: []string{key}}); err != nil {
+		return err
+	}
+
+	return tc.l1.SetWithTags(ctx, key, value, tc.l1Expiration(expiration), tags...)
+}
+
+// InvalidateTags removes every entry carrying any of the tags from both tiers
+// and on every replica. L1 copies filled from L2 carry no tags, so replicas
+// are also sent the keys L2 removed. The count is of entries removed from L2
+func (tc *TieredCache) InvalidateTags(ctx context.Context, tags ...string) (int64, error) {
+	deleted, keys, err := tc.l2.invalidateTags(ctx, tags)
+
+	// Drop what L2 removed even if it failed partway
+	if invErr := tc.invalidate(ctx, invalidation{Keys: keys, Tags: tags}); err == nil {
+		err = invErr
+	}
+	return deleted, err
+}
+
+// Delete removes a value from both tiers and on every replica
+func (tc *TieredCache) Delete(ctx context.Context, key string) error {
+	if err := tc.l2.Delete(ctx, key); err != nil {
+		return err
+	}
+	return tc.invalidate(ctx, invalidation{Keys: []string{key}})
+}
+
+// Exists checks if a key exists in either tier
+func (tc *TieredCache) Exists(ctx context.Context, key string) (bool, error) {
+	if exists, _ := tc.l1.Exists(ctx, key); exists {
+		return true, nil
+	}
+	return tc.l2.Exists(ctx, key)
+}
+
+// Flush removes all keys from both tiers and on every replica
+func (tc *TieredCache) Flush(ctx context.Context) error {
+	_, err := tc.DeletePattern(ctx, "*")
+	return err
+}
+
+// InvalidatePattern removes all keys matching a glob pattern from both tiers
+// and on every replica, see MatchPattern
+func (tc *TieredCache) InvalidatePattern(ctx context.Context, pattern string) error {
+	_, err := tc.DeletePattern(ctx, pattern)
+	return err
+}
+
+// DeletePattern removes all keys matching a glob pattern from both tiers and
+// on every replica. The count is of keys removed from L2
+func (tc *TieredCache) DeletePattern(ctx context.Context, pattern string) (int64, error) {
+	deleted, err := tc.l2.DeletePattern(ctx, pattern)
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, tc.invalidate(ctx, invalidation{Patterns: []string{pattern}})
+}
+
+// Close stops listening for invalidations and closes both tiers
+func (tc *TieredCache) Close() error {
+	var err error
+	tc.closeOnce.Do(func() {
+		close(tc.done)
+		tc.pubsub.Close()
+		tc.l1.Close()
+		err = tc.l2.Close()
+	})
+	return err
+}
+
+// Evictions returns how many entries L1 has evicted
+func (tc *TieredCache) Evictions() uint64 {
+	return tc.l1.Evictions()
+}
+
+// GetStats returns hit ratios for each tier along with the L1 cache stats
+func (tc *TieredCache) GetStats() map[string]interface{} {
+	l1 := tc.l1Stats.snapshot()
+	for k, v := range tc.l1.GetStats() {
+		l1[k] = v
+	}
+
+	return map[string]interface{}{
+		"l1":     l1,
+		"l2":     tc.l2Stats.snapshot(),
+		"l1_ttl": tc.opts.L1TTL.String(),
+	}
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// WarmupFunc fills c with values a module expects to be hot after a deploy
+type WarmupFunc func(ctx context.Context, c Cache) error
+
+// WarmupOptions bounds a warm-up run
+type WarmupOptions struct {
+	// Concurrency is how many loaders run at once
+	Concurrency int
+	// Timeout bounds each loader; zero leaves only the caller's context
+	Timeout time.Duration
+}
+
+// DefaultWarmupOptions returns default warm-up options
+func DefaultWarmupOptions() WarmupOptions {
+	return WarmupOptions{
+		Concurrency: 4,
+		Timeout:     30 * time.Second,
+	}
+}
+
+// warmup is a named loader
+type warmup struct {
+	name string
+	fn   WarmupFunc
+}
+
+// WarmupRegistry collects loaders to run against a cache at startup
+type WarmupRegistry struct {
+	mu      sync.Mutex
+	warmups []warmup
+}
+
+// NewWarmupRegistry creates an empty registry
+func NewWarmupRegistry() *WarmupRegistry {
+	return &WarmupRegistry{}
+}
+
+// Register adds a loader under name. Loaders run in no particular order
+func (r *WarmupRegistry) Register(name string, fn WarmupFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.warmups = append(r.warmups, warmup{name: name, fn: fn})
+}
+
+// Names returns the registered loader names
+func (r *WarmupRegistry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, len(r.warmups))
+	for i, w := range r.warmups {
+		names[i] = w.name
+	}
+	return names
+}
+
+// Run calls every loader with at most opts.Concurrency running at once. A
+// failing loader does not stop the others; their errors are joined
+func (r *WarmupRegistry) Run(ctx context.Context, c Cache, opts WarmupOptions) error {
+	r.mu.Lock()
+	warmups := append([]warmup(nil), r.warmups...)
+	r.mu.Unlock()
+
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultWarmupOptions().Concurrency
+	}
+
+	var (
+		group errgroup.Group
+		mu    sync.Mutex
+		errs  []error
+	)
+	group.SetLimit(opts.Concurrency)
+
+	for _, w := range warmups {
+		group.Go(func() error {
+			loadCtx := ctx
+			if opts.Timeout > 0 {
+				var cancel context.CancelFunc
+				loadCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+				defer cancel()
+			}
+
+			if err := w.fn(loadCtx, c); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("warm up %s: %w", w.name, err))
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	group.Wait()
+
+	return errors.Join(errs...)
+}
+
+// defaultWarmups is the registry modules register with from init
+var defaultWarmups = NewWarmupRegistry()
+
+// RegisterWarmup adds a loader to the default registry
+func RegisterWarmup(name string, fn WarmupFunc) {
+	defaultWarmups.Register(name, fn)
+}
+
+// RunWarmups runs the loaders in the default registry against c
+func RunWarmups(ctx context.Context, c Cache, opts WarmupOptions) error {
+	return defaultWarmups.Run(ctx, c, opts)
+}
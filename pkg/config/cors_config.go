@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"xanny-go/pkg/logger"
+)
+
+type CORSPolicy struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	MaxAge           time.Duration
+	AllowCredentials bool
+}
+
+func GetCORSPolicy() CORSPolicy {
+	config := GetConfig()
+	return CORSPolicy{
+		AllowedOrigins:   config.CORS_ALLOWED_ORIGINS,
+		AllowedMethods:   config.CORS_ALLOWED_METHODS,
+		AllowedHeaders:   config.CORS_ALLOWED_HEADERS,
+		ExposedHeaders:   config.CORS_EXPOSED_HEADERS,
+		MaxAge:           config.CORS_MAX_AGE,
+		AllowCredentials: config.CORS_ALLOW_CREDENTIALS,
+	}
+}
+
+func (p CORSPolicy) AllowsAnyOrigin() bool {
+	for _, origin := range p.AllowedOrigins {
+		if origin == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsOrigin reports whether origin matches one of the allowed origins.
+// Entries are either exact origins or wildcard-subdomain patterns such as
+// "https://*.example.com", which match any subdomain but not the apex.
+func (p CORSPolicy) AllowsOrigin(origin string) bool {
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+
+		scheme, suffix, ok := strings.Cut(allowed, "*")
+		if !ok || len(origin) <= len(scheme)+len(suffix) {
+			continue
+		}
+
+		if !strings.HasPrefix(strings.ToLower(origin), strings.ToLower(scheme)) || !strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) {
+			continue
+		}
+
+		subdomain := origin[len(scheme) : len(origin)-len(suffix)]
+		if !strings.ContainsAny(subdomain, "/:@") {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate rejects malformed policies outright. Combinations that are unsafe
+// (credentials with wildcards, plain-http origins) fail in production and are
+// only logged as warnings elsewhere.
+func (p CORSPolicy) Validate(production bool) error {
+	if len(p.AllowedOrigins) == 0 {
+		return errors.New("cors: at least one allowed origin is required")
+	}
+
+	for _, origin := range p.AllowedOrigins {
+		if err := validateOriginPattern(origin); err != nil {
+			return err
+		}
+	}
+
+	var unsafe []string
+	if p.AllowCredentials && p.AllowsAnyOrigin() {
+		unsafe = append(unsafe, "wildcard origin \"*\" with credentials")
+	}
+
+	if p.AllowCredentials {
+		for _, header := range p.AllowedHeaders {
+			if header == "*" {
+				unsafe = append(unsafe, "wildcard header \"*\" with credentials")
+				break
+			}
+		}
+	}
+
+	for _, origin := range p.AllowedOrigins {
+		if strings.HasPrefix(origin, "http://") && !isLoopbackOrigin(origin) {
+			unsafe = append(unsafe, fmt.Sprintf("insecure origin %q", origin))
+		}
+	}
+
+	if len(unsafe) == 0 {
+		return nil
+	}
+
+	if production {
+		return fmt.Errorf("cors: unsafe policy in production: %s", strings.Join(unsafe, "; "))
+	}
+
+	for _, reason := range unsafe {
+		logger.Warning("CORS policy allows %s; this will be rejected in production", reason)
+	}
+	return nil
+}
+
+func validateOriginPattern(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+
+	if strings.Count(origin, "*") > 1 {
+		return fmt.Errorf("cors: origin %q may contain at most one wildcard", origin)
+	}
+
+	candidate := origin
+	if scheme, suffix, ok := strings.Cut(origin, "*"); ok {
+		if !strings.HasSuffix(scheme, "://") || !strings.HasPrefix(suffix, ".") || strings.Count(suffix, ".") < 2 {
+			return fmt.Errorf("cors: origin pattern %q must look like scheme://*.domain.tld", origin)
+		}
+		candidate = scheme + "wildcard" + suffix
+	}
+
+	parsed, err := url.Parse(candidate)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" || (parsed.Path != "" && parsed.Path != "/") {
+		return fmt.Errorf("cors: origin %q must be scheme://host[:port]", origin)
+	}
+
+	return nil
+}
+
+func isLoopbackOrigin(origin string) bool {
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	host := parsed.Hostname()
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
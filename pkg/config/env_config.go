@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"xanny-go/pkg/logger"
 
@@ -27,6 +30,68 @@ type Config struct {
 	SMTP_PASSWORD   string
 	SMTP_SERVER     string
 	SMTP_PORT       string
+
+	REDIS_MODE              string
+	REDIS_USERNAME          string
+	REDIS_DB                int
+	REDIS_SENTINEL_MASTER   string
+	REDIS_SENTINEL_PASSWORD string
+	REDIS_TLS               bool
+	REDIS_TLS_CA_FILE       string
+	REDIS_TLS_SERVER_NAME   string
+	REDIS_POOL_SIZE         int
+	REDIS_MIN_IDLE_CONNS    int
+	REDIS_DIAL_TIMEOUT      time.Duration
+	REDIS_READ_TIMEOUT      time.Duration
+	REDIS_WRITE_TIMEOUT     time.Duration
+
+	HTTP_READ_TIMEOUT        time.Duration
+	HTTP_READ_HEADER_TIMEOUT time.Duration
+	HTTP_WRITE_TIMEOUT       time.Duration
+	HTTP_IDLE_TIMEOUT        time.Duration
+	REQUEST_TIMEOUT          time.Duration
+	HTTP_MAX_BODY_BYTES      int
+
+	CACHE_TYPE               string
+	CACHE_DEFAULT_TTL        time.Duration
+	CACHE_MAX_SIZE           int
+	CACHE_MAX_BYTES          int
+	CACHE_EVICTION_POLICY    string
+	CACHE_PREFIX             string
+	CACHE_L1_TTL             time.Duration
+	CACHE_SNAPSHOT_PATH      string
+	CACHE_RESPONSE_TTL       time.Duration
+	QUERY_CACHE_TTL          time.Duration
+	QUERY_CACHE_NEGATIVE_TTL time.Duration
+
+	SESSION_COOKIE_NAME     string
+	SESSION_COOKIE_DOMAIN   string
+	SESSION_COOKIE_SECURE   bool
+	SESSION_COOKIE_SAMESITE string
+	SESSION_IDLE_TTL        time.Duration
+	SESSION_ABSOLUTE_TTL    time.Duration
+
+	CLIENT_RETENTION_DAYS     int
+	CLIENT_RETENTION_MODE     string
+	CLIENT_RETENTION_INTERVAL time.Duration
+	CLIENT_IP_ANONYMIZATION   string
+	CLIENT_IP_HASH_KEY        string
+
+	CORS_ALLOWED_ORIGINS   []string
+	CORS_ALLOWED_METHODS   []string
+	CORS_ALLOWED_HEADERS   []string
+	CORS_EXPOSED_HEADERS   []string
+	CORS_MAX_AGE           time.Duration
+	CORS_ALLOW_CREDENTIALS bool
+
+	SECURITY_HSTS_MAX_AGE            time.Duration
+	SECURITY_HSTS_INCLUDE_SUBDOMAINS bool
+	SECURITY_HSTS_PRELOAD            bool
+	SECURITY_FRAME_OPTIONS           string
+	SECURITY_REFERRER_POLICY         string
+	SECURITY_PERMISSIONS_POLICY      string
+	SECURITY_CSP                     string
+	SECURITY_CSP_REPORT_URI          string
 }
 
 var globalConfig *Config
@@ -52,6 +117,68 @@ func InitConfig() {
 		SMTP_PASSWORD:   getEnv("SMTP_PASSWORD"),
 		SMTP_SERVER:     getEnv("SMTP_SERVER"),
 		SMTP_PORT:       getEnv("SMTP_PORT"),
+
+		REDIS_MODE:              getEnvOrDefault("REDIS_MODE", RedisModeStandalone),
+		REDIS_USERNAME:          os.Getenv("REDIS_USERNAME"),
+		REDIS_DB:                getEnvInt("REDIS_DB", 0),
+		REDIS_SENTINEL_MASTER:   os.Getenv("REDIS_SENTINEL_MASTER"),
+		REDIS_SENTINEL_PASSWORD: os.Getenv("REDIS_SENTINEL_PASSWORD"),
+		REDIS_TLS:               getEnvBool("REDIS_TLS", false),
+		REDIS_TLS_CA_FILE:       os.Getenv("REDIS_TLS_CA_FILE"),
+		REDIS_TLS_SERVER_NAME:   os.Getenv("REDIS_TLS_SERVER_NAME"),
+		REDIS_POOL_SIZE:         getEnvInt("REDIS_POOL_SIZE", 0),
+		REDIS_MIN_IDLE_CONNS:    getEnvInt("REDIS_MIN_IDLE_CONNS", 0),
+		REDIS_DIAL_TIMEOUT:      getEnvDuration("REDIS_DIAL_TIMEOUT", 5*time.Second),
+		REDIS_READ_TIMEOUT:      getEnvDuration("REDIS_READ_TIMEOUT", 3*time.Second),
+		REDIS_WRITE_TIMEOUT:     getEnvDuration("REDIS_WRITE_TIMEOUT", 3*time.Second),
+
+		HTTP_READ_TIMEOUT:        getEnvDuration("HTTP_READ_TIMEOUT", 15*time.Second),
+		HTTP_READ_HEADER_TIMEOUT: getEnvDuration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		HTTP_WRITE_TIMEOUT:       getEnvDuration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		HTTP_IDLE_TIMEOUT:        getEnvDuration("HTTP_IDLE_TIMEOUT", 60*time.Second),
+		REQUEST_TIMEOUT:          getEnvDuration("REQUEST_TIMEOUT", 10*time.Second),
+		HTTP_MAX_BODY_BYTES:      getEnvInt("HTTP_MAX_BODY_BYTES", 1<<20),
+
+		CACHE_TYPE:               getEnvOrDefault("CACHE_TYPE", "redis"),
+		CACHE_DEFAULT_TTL:        getEnvDuration("CACHE_DEFAULT_TTL", 5*time.Minute),
+		CACHE_MAX_SIZE:           getEnvInt("CACHE_MAX_SIZE", 1000),
+		CACHE_MAX_BYTES:          getEnvInt("CACHE_MAX_BYTES", 0),
+		CACHE_EVICTION_POLICY:    getEnvOrDefault("CACHE_EVICTION_POLICY", "lru"),
+		CACHE_PREFIX:             getEnvOrDefault("CACHE_PREFIX", "cache:"),
+		CACHE_L1_TTL:             getEnvDuration("CACHE_L1_TTL", 30*time.Second),
+		CACHE_SNAPSHOT_PATH:      os.Getenv("CACHE_SNAPSHOT_PATH"),
+		CACHE_RESPONSE_TTL:       getEnvDuration("CACHE_RESPONSE_TTL", time.Minute),
+		QUERY_CACHE_TTL:          getEnvDuration("QUERY_CACHE_TTL", time.Minute),
+		QUERY_CACHE_NEGATIVE_TTL: getEnvDuration("QUERY_CACHE_NEGATIVE_TTL", 15*time.Second),
+
+		SESSION_COOKIE_NAME:     getEnvOrDefault("SESSION_COOKIE_NAME", "session_id"),
+		SESSION_COOKIE_DOMAIN:   os.Getenv("SESSION_COOKIE_DOMAIN"),
+		SESSION_COOKIE_SECURE:   getEnvBool("SESSION_COOKIE_SECURE", true),
+		SESSION_COOKIE_SAMESITE: getEnvOrDefault("SESSION_COOKIE_SAMESITE", "lax"),
+		SESSION_IDLE_TTL:        getEnvDuration("SESSION_IDLE_TTL", 30*time.Minute),
+		SESSION_ABSOLUTE_TTL:    getEnvDuration("SESSION_ABSOLUTE_TTL", 24*time.Hour),
+
+		CLIENT_RETENTION_DAYS:     getEnvInt("CLIENT_RETENTION_DAYS", 90),
+		CLIENT_RETENTION_MODE:     getEnvOrDefault("CLIENT_RETENTION_MODE", "aggregate"),
+		CLIENT_RETENTION_INTERVAL: getEnvDuration("CLIENT_RETENTION_INTERVAL", 24*time.Hour),
+		CLIENT_IP_ANONYMIZATION:   getEnvOrDefault("CLIENT_IP_ANONYMIZATION", "truncate"),
+		CLIENT_IP_HASH_KEY:        os.Getenv("CLIENT_IP_HASH_KEY"),
+
+		CORS_ALLOWED_ORIGINS:   getEnvList("CORS_ALLOWED_ORIGINS", []string{getEnv("FRONTEND_URL")}),
+		CORS_ALLOWED_METHODS:   getEnvList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+		CORS_ALLOWED_HEADERS:   getEnvList("CORS_ALLOWED_HEADERS", []string{"Origin", "Content-Type", "Accept", "Authorization", "Idempotency-Key", "X-CSRF-Token"}),
+		CORS_EXPOSED_HEADERS:   getEnvList("CORS_EXPOSED_HEADERS", []string{"Content-Length", "Idempotent-Replayed"}),
+		CORS_MAX_AGE:           getEnvDuration("CORS_MAX_AGE", 12*time.Hour),
+		CORS_ALLOW_CREDENTIALS: getEnvBool("CORS_ALLOW_CREDENTIALS", true),
+
+		SECURITY_HSTS_MAX_AGE:            getEnvDuration("SECURITY_HSTS_MAX_AGE", 365*24*time.Hour),
+		SECURITY_HSTS_INCLUDE_SUBDOMAINS: getEnvBool("SECURITY_HSTS_INCLUDE_SUBDOMAINS", true),
+		SECURITY_HSTS_PRELOAD:            getEnvBool("SECURITY_HSTS_PRELOAD", false),
+		SECURITY_FRAME_OPTIONS:           getEnvOrDefault("SECURITY_FRAME_OPTIONS", "DENY"),
+		SECURITY_REFERRER_POLICY:         getEnvOrDefault("SECURITY_REFERRER_POLICY", "strict-origin-when-cross-origin"),
+		SECURITY_PERMISSIONS_POLICY:      getEnvOrDefault("SECURITY_PERMISSIONS_POLICY", "camera=(), microphone=(), geolocation=(), payment=(), usb=()"),
+		SECURITY_CSP:                     os.Getenv("SECURITY_CSP"),
+		SECURITY_CSP_REPORT_URI:          os.Getenv("SECURITY_CSP_REPORT_URI"),
 	}
 
 	globalConfig = config
@@ -84,6 +211,55 @@ func GetSMTPPassword() string   { return GetConfig().SMTP_PASSWORD }
 func GetSMTPServer() string     { return GetConfig().SMTP_SERVER }
 func GetSMTPPort() string       { return GetConfig().SMTP_PORT }
 
+// GetRedisOptions returns the connection settings for NewRedisClient.
+// REDIS_ADDR may list several comma-separated addresses: the sentinels in
+// sentinel mode, or seed nodes in cluster mode
+func GetRedisOptions() RedisOptions {
+	cfg := GetConfig()
+	return RedisOptions{
+		Mode:             cfg.REDIS_MODE,
+		Addrs:            splitList(cfg.REDIS_ADDR),
+		Username:         cfg.REDIS_USERNAME,
+		Password:         cfg.REDIS_PASS,
+		DB:               cfg.REDIS_DB,
+		MasterName:       cfg.REDIS_SENTINEL_MASTER,
+		SentinelPassword: cfg.REDIS_SENTINEL_PASSWORD,
+		TLS:              cfg.REDIS_TLS,
+		TLSCAFile:        cfg.REDIS_TLS_CA_FILE,
+		TLSServerName:    cfg.REDIS_TLS_SERVER_NAME,
+		PoolSize:         cfg.REDIS_POOL_SIZE,
+		MinIdleConns:     cfg.REDIS_MIN_IDLE_CONNS,
+		DialTimeout:      cfg.REDIS_DIAL_TIMEOUT,
+		ReadTimeout:      cfg.REDIS_READ_TIMEOUT,
+		WriteTimeout:     cfg.REDIS_WRITE_TIMEOUT,
+	}
+}
+
+func GetHTTPReadTimeout() time.Duration       { return GetConfig().HTTP_READ_TIMEOUT }
+func GetHTTPReadHeaderTimeout() time.Duration { return GetConfig().HTTP_READ_HEADER_TIMEOUT }
+func GetHTTPWriteTimeout() time.Duration      { return GetConfig().HTTP_WRITE_TIMEOUT }
+func GetHTTPIdleTimeout() time.Duration       { return GetConfig().HTTP_IDLE_TIMEOUT }
+func GetRequestTimeout() time.Duration        { return GetConfig().REQUEST_TIMEOUT }
+func GetHTTPMaxBodyBytes() int64              { return int64(GetConfig().HTTP_MAX_BODY_BYTES) }
+
+func GetCacheType() string                    { return GetConfig().CACHE_TYPE }
+func GetCacheDefaultTTL() time.Duration       { return GetConfig().CACHE_DEFAULT_TTL }
+func GetCacheMaxSize() int                    { return GetConfig().CACHE_MAX_SIZE }
+func GetCacheMaxBytes() int64                 { return int64(GetConfig().CACHE_MAX_BYTES) }
+func GetCacheEvictionPolicy() string          { return GetConfig().CACHE_EVICTION_POLICY }
+func GetCachePrefix() string                  { return GetConfig().CACHE_PREFIX }
+func GetCacheL1TTL() time.Duration            { return GetConfig().CACHE_L1_TTL }
+func GetCacheSnapshotPath() string            { return GetConfig().CACHE_SNAPSHOT_PATH }
+func GetCacheResponseTTL() time.Duration      { return GetConfig().CACHE_RESPONSE_TTL }
+func GetQueryCacheTTL() time.Duration         { return GetConfig().QUERY_CACHE_TTL }
+func GetQueryCacheNegativeTTL() time.Duration { return GetConfig().QUERY_CACHE_NEGATIVE_TTL }
+
+func GetClientRetentionDays() int               { return GetConfig().CLIENT_RETENTION_DAYS }
+func GetClientRetentionMode() string            { return GetConfig().CLIENT_RETENTION_MODE }
+func GetClientRetentionInterval() time.Duration { return GetConfig().CLIENT_RETENTION_INTERVAL }
+func GetClientIPAnonymization() string          { return GetConfig().CLIENT_IP_ANONYMIZATION }
+func GetClientIPHashKey() string                { return GetConfig().CLIENT_IP_HASH_KEY }
+
 func IsProduction() bool  { return GetEnvironment() == "production" }
 func IsDevelopment() bool { return GetEnvironment() == "development" }
 
@@ -94,3 +270,69 @@ func getEnv(key string) string {
 	}
 	return value
 }
+
+func getEnvOrDefault(key, fallback string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		logger.PanicError("Environment variable %s must be an integer, got %q", key, value)
+	}
+	return parsed
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.PanicError("Environment variable %s must be a boolean, got %q", key, value)
+	}
+	return parsed
+}
+
+func getEnvList(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	return splitList(value)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		logger.PanicError("Environment variable %s must be a duration, got %q", key, value)
+	}
+	return parsed
+}
@@ -2,22 +2,160 @@ package config
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
 	"xanny-go/pkg/exceptions"
 
 	"github.com/go-redis/redis/v8"
 )
 
-var RedisClient *redis.Client
+// Redis deployment modes accepted in REDIS_MODE
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
+// RedisClient is the process-wide Redis client, shared by the blacklist
+// helpers and the cache
+var RedisClient redis.UniversalClient
+
+// RedisOptions describes how to reach Redis in any deployment mode
+type RedisOptions struct {
+	Mode string
+	// Addrs is the server in standalone mode, the sentinels in sentinel mode
+	// and the seed nodes in cluster mode
+	Addrs    []string
+	Username string
+	Password string
+	// DB must be 0 in cluster mode
+	DB int
+
+	MasterName       string
+	SentinelPassword string
+
+	TLS bool
+	// TLSCAFile verifies the server against this PEM bundle instead of the
+	// system roots
+	TLSCAFile     string
+	TLSServerName string
+
+	// Zero values keep the go-redis defaults
+	PoolSize     int
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// NewRedisClient creates a client for the configured mode. It does not
+// connect; errors are configuration mistakes only
+func NewRedisClient(opts RedisOptions) (redis.UniversalClient, error) {
+	if len(opts.Addrs) == 0 {
+		return nil, fmt.Errorf("redis: at least one address is required")
+	}
+
+	var tlsConfig *tls.Config
+	if opts.TLS {
+		var err error
+		if tlsConfig, err = redisTLSConfig(opts); err != nil {
+			return nil, err
+		}
+	}
+
+	switch opts.Mode {
+	case "", RedisModeStandalone:
+		if len(opts.Addrs) > 1 {
+			return nil, fmt.Errorf("redis: standalone mode takes one address, got %d", len(opts.Addrs))
+		}
+		return redis.NewClient(&redis.Options{
+			Addr:         opts.Addrs[0],
+			Username:     opts.Username,
+			Password:     opts.Password,
+			DB:           opts.DB,
+			TLSConfig:    tlsConfig,
+			PoolSize:     opts.PoolSize,
+			MinIdleConns: opts.MinIdleConns,
+			DialTimeout:  opts.DialTimeout,
+			ReadTimeout:  opts.ReadTimeout,
+			WriteTimeout: opts.WriteTimeout,
+		}), nil
+
+	case RedisModeSentinel:
+		if opts.MasterName == "" {
+			return nil, fmt.Errorf("redis: sentinel mode requires REDIS_SENTINEL_MASTER")
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       opts.MasterName,
+			SentinelAddrs:    opts.Addrs,
+			SentinelPassword: opts.SentinelPassword,
+			Username:         opts.Username,
+			Password:         opts.Password,
+			DB:               opts.DB,
+			TLSConfig:        tlsConfig,
+			PoolSize:         opts.PoolSize,
+			MinIdleConns:     opts.MinIdleConns,
+			DialTimeout:      opts.DialTimeout,
+			ReadTimeout:      opts.ReadTimeout,
+			WriteTimeout:     opts.WriteTimeout,
+		}), nil
+
+	case RedisModeCluster:
+		if opts.DB != 0 {
+			return nil, fmt.Errorf("redis: cluster mode only supports DB 0")
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        opts.Addrs,
+			Username:     opts.Username,
+			Password:     opts.Password,
+			TLSConfig:    tlsConfig,
+			PoolSize:     opts.PoolSize,
+			MinIdleConns: opts.MinIdleConns,
+			DialTimeout:  opts.DialTimeout,
+			ReadTimeout:  opts.ReadTimeout,
+			WriteTimeout: opts.WriteTimeout,
+		}), nil
+
+	default:
+		return nil, fmt.Errorf("redis: unknown mode %q, want %s, %s or %s", opts.Mode, RedisModeStandalone, RedisModeSentinel, RedisModeCluster)
+	}
+}
+
+// redisTLSConfig builds the TLS settings, trusting TLSCAFile when given
+func redisTLSConfig(opts RedisOptions) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: opts.TLSServerName,
+	}
+
+	if opts.TLSCAFile != "" {
+		pem, err := os.ReadFile(opts.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis: reading CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis: no certificates found in %s", opts.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
 
 func InitRedis() *exceptions.Exception {
-	RedisClient = redis.NewClient(&redis.Options{
-		Addr:     GetRedisAddr(),
-		Password: GetRedisPass(),
-		DB:       0,
-	})
+	client, err := NewRedisClient(GetRedisOptions())
+	if err != nil {
+		return exceptions.NewException(500, "Invalid Redis config: "+err.Error())
+	}
+	RedisClient = client
 
 	ctx := context.Background()
-	_, err := RedisClient.Ping(ctx).Result()
+	_, err = RedisClient.Ping(ctx).Result()
 	if err != nil {
 		return exceptions.NewException(500, "Failed to connect to Redis: "+err.Error())
 	}
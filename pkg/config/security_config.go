@@ -0,0 +1,39 @@
+package config
+
+import "time"
+
+type SecurityHeadersConfig struct {
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	FrameOptions          string
+	ReferrerPolicy        string
+	PermissionsPolicy     string
+	ContentSecurityPolicy string
+	CSPReportURI          string
+	Development           bool
+}
+
+// GetSecurityHeadersConfig returns the configured security headers. HSTS is
+// always disabled in development so browsers do not pin localhost to HTTPS.
+func GetSecurityHeadersConfig() SecurityHeadersConfig {
+	config := GetConfig()
+
+	securityConfig := SecurityHeadersConfig{
+		HSTSMaxAge:            config.SECURITY_HSTS_MAX_AGE,
+		HSTSIncludeSubdomains: config.SECURITY_HSTS_INCLUDE_SUBDOMAINS,
+		HSTSPreload:           config.SECURITY_HSTS_PRELOAD,
+		FrameOptions:          config.SECURITY_FRAME_OPTIONS,
+		ReferrerPolicy:        config.SECURITY_REFERRER_POLICY,
+		PermissionsPolicy:     config.SECURITY_PERMISSIONS_POLICY,
+		ContentSecurityPolicy: config.SECURITY_CSP,
+		CSPReportURI:          config.SECURITY_CSP_REPORT_URI,
+		Development:           IsDevelopment(),
+	}
+
+	if securityConfig.Development {
+		securityConfig.HSTSMaxAge = 0
+	}
+
+	return securityConfig
+}
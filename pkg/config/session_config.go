@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"xanny-go/pkg/logger"
+)
+
+type SessionConfig struct {
+	CookieName   string
+	CookieDomain string
+	CookieSecure bool
+	// CookieSameSite is one of lax, strict or none
+	CookieSameSite string
+	IdleTTL        time.Duration
+	AbsoluteTTL    time.Duration
+}
+
+func GetSessionConfig() SessionConfig {
+	config := GetConfig()
+	return SessionConfig{
+		CookieName:     config.SESSION_COOKIE_NAME,
+		CookieDomain:   config.SESSION_COOKIE_DOMAIN,
+		CookieSecure:   config.SESSION_COOKIE_SECURE,
+		CookieSameSite: config.SESSION_COOKIE_SAMESITE,
+		IdleTTL:        config.SESSION_IDLE_TTL,
+		AbsoluteTTL:    config.SESSION_ABSOLUTE_TTL,
+	}
+}
+
+// SameSite returns the http.SameSite value for CookieSameSite
+func (c SessionConfig) SameSite() http.SameSite {
+	switch strings.ToLower(c.CookieSameSite) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// Validate rejects malformed settings outright. An insecure cookie fails in
+// production and is only logged as a warning elsewhere, so plain-HTTP local
+// development still works.
+func (c SessionConfig) Validate(production bool) error {
+	if c.CookieName == "" {
+		return fmt.Errorf("session: cookie name is required")
+	}
+
+	switch strings.ToLower(c.CookieSameSite) {
+	case "lax", "strict", "none":
+	default:
+		return fmt.Errorf("session: unknown SameSite mode %q", c.CookieSameSite)
+	}
+
+	if c.IdleTTL <= 0 || c.AbsoluteTTL < c.IdleTTL {
+		return fmt.Errorf("session: idle TTL must be positive and no longer than the absolute TTL")
+	}
+
+	if c.SameSite() == http.SameSiteNoneMode && !c.CookieSecure {
+		return fmt.Errorf("session: SameSite=None cookies must be Secure")
+	}
+
+	if !c.CookieSecure {
+		if production {
+			return fmt.Errorf("session: cookies must be Secure in production")
+		}
+		logger.Warning("Session cookies are not Secure; this will be rejected in production")
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package exceptions
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strings"
@@ -14,6 +15,18 @@ func ParseGormError(tx *gorm.DB, err error) *Exception {
 	}
 	
 	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Exception{
+			Message:    ErrRequestTimeout,
+			Status: http.StatusGatewayTimeout,
+		}
+
+	case errors.Is(err, context.Canceled):
+		return &Exception{
+			Message:    ErrRequestCanceled,
+			Status: http.StatusServiceUnavailable,
+		}
+
 	case errors.Is(err, gorm.ErrRecordNotFound):
 		return &Exception{
 			Message:    "Record not found",
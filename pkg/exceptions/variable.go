@@ -27,4 +27,16 @@ const (
 	ErrInvalidDate               = "invalid date"
 	ErrInvalidTokenStructure     = "invalid token structure"
 	ErrDataNotVerified           = "data not verified"
+	ErrBodyEmpty                 = "request body is empty"
+	ErrBodyMalformed             = "request body contains malformed JSON"
+	ErrBodyTrailingData          = "request body must contain a single JSON value"
+	ErrBodyTooLarge              = "request body is too large"
+	ErrRequestTimeout            = "request timed out"
+	ErrRequestCanceled           = "request was canceled"
+	ErrIdempotencyKeyRequired    = "Idempotency-Key header is required"
+	ErrIdempotencyKeyInvalid     = "invalid Idempotency-Key header"
+	ErrIdempotencyKeyInUse       = "a request with this Idempotency-Key is already in progress"
+	ErrIdempotencyKeyMismatch    = "Idempotency-Key was already used with a different request"
+	ErrSessionRequired           = "session is missing or expired"
+	ErrCSRFTokenInvalid          = "missing or invalid CSRF token"
 )
@@ -0,0 +1,128 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"xanny-go/pkg/exceptions"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// BindJSON decodes the request body into obj, rejecting unknown fields and
+// anything after the first JSON value, then runs the `binding` tag
+// validation. The returned exception names the field that failed.
+func BindJSON(ctx *gin.Context, obj interface{}) *exceptions.Exception {
+	if ctx.Request.Body == nil {
+		return exceptions.NewException(http.StatusBadRequest, exceptions.ErrBodyEmpty)
+	}
+
+	decoder := json.NewDecoder(ctx.Request.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(obj); err != nil {
+		return DecodeException(err)
+	}
+
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		if err != nil {
+			if exc := bodyLimitException(err); exc != nil {
+				return exc
+			}
+		}
+		return exceptions.NewException(http.StatusBadRequest, exceptions.ErrBodyTrailingData)
+	}
+
+	if err := binding.Validator.ValidateStruct(obj); err != nil {
+		return ValidationException(obj, err)
+	}
+
+	return nil
+}
+
+// DecodeException turns a request body read or JSON decode error into a
+// client-facing exception.
+func DecodeException(err error) *exceptions.Exception {
+	if exc := bodyLimitException(err); exc != nil {
+		return exc
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return exceptions.NewException(http.StatusBadRequest, exceptions.ErrBodyEmpty)
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return exceptions.NewException(http.StatusBadRequest, exceptions.ErrBodyMalformed)
+
+	case errors.As(err, &syntaxErr):
+		return exceptions.NewException(http.StatusBadRequest, fmt.Sprintf("%s at position %d", exceptions.ErrBodyMalformed, syntaxErr.Offset))
+
+	case errors.As(err, &typeErr):
+		return exceptions.NewException(http.StatusBadRequest, fmt.Sprintf("field %q must be %s", typeErr.Field, typeErr.Type.String()))
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return exceptions.NewException(http.StatusBadRequest, fmt.Sprintf("field %s is not allowed", field))
+
+	default:
+		return exceptions.NewException(http.StatusBadRequest, exceptions.ErrBadRequest)
+	}
+}
+
+// ValidationException reports the first failed validation rule using the
+// JSON name of the field.
+func ValidationException(obj interface{}, err error) *exceptions.Exception {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) || len(validationErrs) == 0 {
+		return exceptions.NewValidationException(err)
+	}
+
+	fieldErr := validationErrs[0]
+	field := jsonFieldName(obj, fieldErr.StructField())
+
+	message := fmt.Sprintf("field %q failed %q validation", field, fieldErr.Tag())
+	if fieldErr.Param() != "" {
+		message = fmt.Sprintf("field %q failed %q validation (%s)", field, fieldErr.Tag(), fieldErr.Param())
+	}
+
+	return exceptions.NewException(http.StatusBadRequest, message)
+}
+
+func bodyLimitException(err error) *exceptions.Exception {
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		return nil
+	}
+
+	return exceptions.NewException(http.StatusRequestEntityTooLarge, fmt.Sprintf("%s (limit %d bytes)", exceptions.ErrBodyTooLarge, maxBytesErr.Limit))
+}
+
+func jsonFieldName(obj interface{}, structField string) string {
+	t := reflect.TypeOf(obj)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return structField
+	}
+
+	field, ok := t.FieldByName(structField)
+	if !ok {
+		return structField
+	}
+
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return structField
+	}
+	return name
+}
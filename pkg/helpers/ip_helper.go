@@ -0,0 +1,59 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+)
+
+const (
+	IPAnonymizationNone     = "none"
+	IPAnonymizationTruncate = "truncate"
+	IPAnonymizationHash     = "hash"
+)
+
+var (
+	ipv4TruncateMask = net.CIDRMask(24, 32)
+	ipv6TruncateMask = net.CIDRMask(48, 128)
+)
+
+// IPAnonymizer returns the function ClientTracker applies to every client IP
+// before it is stored. Truncation keeps the /24 (IPv4) or /48 (IPv6) network;
+// hashing replaces the address with an HMAC-SHA256 keyed by key.
+func IPAnonymizer(mode, key string) (func(string) string, error) {
+	switch mode {
+	case "", IPAnonymizationNone:
+		return func(ip string) string { return ip }, nil
+	case IPAnonymizationTruncate:
+		return TruncateIP, nil
+	case IPAnonymizationHash:
+		if key == "" {
+			return nil, fmt.Errorf("ip anonymization mode %q requires a hash key", mode)
+		}
+		secret := []byte(key)
+		return func(ip string) string { return HashIP(ip, secret) }, nil
+	default:
+		return nil, fmt.Errorf("unknown ip anonymization mode %q", mode)
+	}
+}
+
+func TruncateIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(ipv4TruncateMask).String()
+	}
+
+	return parsed.Mask(ipv6TruncateMask).String()
+}
+
+func HashIP(ip string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -1,20 +1,17 @@
 package helpers
 
 import (
-	"xanny-go/pkg/config"
+	"context"
 	"time"
-
-	"golang.org/x/net/context"
+	"xanny-go/pkg/config"
 )
 
-var ctx = context.Background()
-
-func SetBlacklistedToken(token string, expiresAt time.Time) error {
+func SetBlacklistedToken(ctx context.Context, token string, expiresAt time.Time) error {
 	ttl := time.Until(expiresAt)
 	return config.RedisClient.Set(ctx, "blacklist:"+token, "1", ttl).Err()
 }
 
-func IsTokenBlacklisted(token string) (bool, error) {
+func IsTokenBlacklisted(ctx context.Context, token string) (bool, error) {
 	val, err := config.RedisClient.Exists(ctx, "blacklist:"+token).Result()
 	return val == 1, err
 }
@@ -0,0 +1,156 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"xanny-go/pkg/cache"
+	"xanny-go/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RetentionModeDelete    = "delete"
+	RetentionModeAggregate = "aggregate"
+)
+
+type ClientRetentionOptions struct {
+	RetentionDays int
+	Mode          string
+	Interval      time.Duration
+	DeleteBatch   int
+	// Locker, when set, makes sure only one replica runs each pass
+	Locker *cache.Locker
+}
+
+const (
+	clientRetentionLock    = "jobs:client_retention"
+	clientRetentionLockTTL = time.Minute
+)
+
+// rollupClientsSQL summarises clients older than the cutoff per day.
+// chr(63) is the question mark, which Exec would otherwise read as a
+// placeholder.
+const rollupClientsSQL = `
+INSERT INTO client_daily_rollups (created_at, updated_at, day, endpoint, browser, os, device, requests, errors, unique_ips, avg_latency_ms)
+SELECT now(), now(), date_trunc('day', created_at), split_part(api, chr(63), 1), browser, os, device,
+	count(*), count(*) FILTER (WHERE status_code >= 500), count(DISTINCT ip), coalesce(avg(latency_ms), 0)
+FROM clients
+WHERE created_at < ?
+	AND created_at >= coalesce((SELECT max(day) + interval '1 day' FROM client_daily_rollups), '-infinity')
+GROUP BY 3, 4, 5, 6, 7`
+
+const deleteClientsSQL = `
+DELETE FROM clients
+WHERE id IN (SELECT id FROM clients WHERE created_at < ? LIMIT ?)`
+
+func (o ClientRetentionOptions) Validate() error {
+	if o.RetentionDays < 0 {
+		return fmt.Errorf("client retention days must not be negative")
+	}
+
+	switch o.Mode {
+	case RetentionModeDelete, RetentionModeAggregate:
+	default:
+		return fmt.Errorf("unknown client retention mode %q", o.Mode)
+	}
+
+	return nil
+}
+
+// StartClientRetention runs the retention pass once at startup and then every
+// Interval until ctx is cancelled. A RetentionDays of zero disables the job.
+func StartClientRetention(ctx context.Context, db *gorm.DB, opts ClientRetentionOptions) {
+	if opts.RetentionDays == 0 {
+		logger.Info("Client retention disabled")
+		return
+	}
+
+	if opts.Interval <= 0 {
+		opts.Interval = 24 * time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			runClientRetentionLocked(ctx, db, opts)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// runClientRetentionLocked runs one retention pass while holding the retention
+// lock, skipping the pass if another replica holds it
+func runClientRetentionLocked(ctx context.Context, db *gorm.DB, opts ClientRetentionOptions) {
+	if opts.Locker != nil {
+		lock, err := opts.Locker.TryAcquire(ctx, clientRetentionLock, clientRetentionLockTTL)
+		if errors.Is(err, cache.ErrLockNotAcquired) {
+			logger.Info("Client retention skipped: running on another instance")
+			return
+		}
+		if err != nil {
+			logger.Error("Client retention lock failed: %v", err)
+			return
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = lock.KeepAlive(ctx)
+		defer func() {
+			cancel()
+			if err := lock.Release(context.Background()); err != nil {
+				logger.Warning("Client retention lock release failed: %v", err)
+			}
+		}()
+	}
+
+	if _, err := RunClientRetention(ctx, db, opts); err != nil {
+		logger.Error("Client retention failed: %v", err)
+	}
+}
+
+// RunClientRetention removes client rows older than the retention window. In
+// aggregate mode they are first summarised into client_daily_rollups. The
+// cutoff is aligned to midnight UTC and days already rolled up are skipped, so
+// each day is rolled up exactly once even if an earlier pass stopped midway
+// through deleting. Each delete batch commits on its own so a large backlog
+// never holds locks for the whole pass.
+func RunClientRetention(ctx context.Context, db *gorm.DB, opts ClientRetentionOptions) (int64, error) {
+	if opts.DeleteBatch <= 0 {
+		opts.DeleteBatch = 5000
+	}
+
+	cutoff := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -opts.RetentionDays)
+	start := time.Now()
+	db = db.WithContext(ctx)
+
+	if opts.Mode == RetentionModeAggregate {
+		if err := db.Exec(rollupClientsSQL, cutoff).Error; err != nil {
+			return 0, fmt.Errorf("rollup clients: %w", err)
+		}
+	}
+
+	var deleted int64
+	for {
+		result := db.Exec(deleteClientsSQL, cutoff, opts.DeleteBatch)
+		if result.Error != nil {
+			return deleted, fmt.Errorf("delete clients: %w", result.Error)
+		}
+
+		deleted += result.RowsAffected
+		if result.RowsAffected < int64(opts.DeleteBatch) {
+			break
+		}
+	}
+
+	logger.Info("Client retention removed %d rows older than %s in %s", deleted, cutoff.Format(time.DateOnly), time.Since(start))
+	return deleted, nil
+}
@@ -0,0 +1,96 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type recordedStatement struct {
+	sql  string
+	vars []interface{}
+}
+
+// newDryRunDB returns a Postgres session that builds statements without
+// sending them, and the statements it has built so far
+func newDryRunDB(t *testing.T) (*gorm.DB, *[]recordedStatement) {
+	t.Helper()
+
+	sqlDB, err := sql.Open("pgx", "postgres://localhost/dry_run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var statements []recordedStatement
+	err = db.Callback().Raw().After("gorm:raw").Register("test:record", func(tx *gorm.DB) {
+		statements = append(statements, recordedStatement{sql: tx.Statement.SQL.String(), vars: tx.Statement.Vars})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db, &statements
+}
+
+func TestRunClientRetentionRollupSQL(t *testing.T) {
+	db, statements := newDryRunDB(t)
+
+	_, err := RunClientRetention(context.Background(), db, ClientRetentionOptions{
+		RetentionDays: 30,
+		Mode:          RetentionModeAggregate,
+		DeleteBatch:   100,
+	})
+	if err != nil {
+		t.Fatalf("RunClientRetention: %v", err)
+	}
+
+	if len(*statements) != 2 {
+		t.Fatalf("got %d statements, want the rollup and one delete batch", len(*statements))
+	}
+	rollup, del := (*statements)[0], (*statements)[1]
+
+	cutoff := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -30)
+
+	if !strings.Contains(rollup.sql, "split_part(api, chr(63), 1)") {
+		t.Errorf("rollup does not strip query strings with chr(63):\n%s", rollup.sql)
+	}
+	if !strings.Contains(rollup.sql, "WHERE created_at < $1") || strings.Contains(rollup.sql, "$2") {
+		t.Errorf("rollup should bind only the cutoff, as $1:\n%s", rollup.sql)
+	}
+	if len(rollup.vars) != 1 || rollup.vars[0] != cutoff {
+		t.Errorf("rollup vars = %v, want [%v]", rollup.vars, cutoff)
+	}
+
+	if !strings.Contains(del.sql, "created_at < $1 LIMIT $2") {
+		t.Errorf("delete batch placeholders:\n%s", del.sql)
+	}
+	if len(del.vars) != 2 || del.vars[0] != cutoff || del.vars[1] != 100 {
+		t.Errorf("delete vars = %v, want [%v 100]", del.vars, cutoff)
+	}
+}
+
+func TestRunClientRetentionDeleteModeSkipsRollup(t *testing.T) {
+	db, statements := newDryRunDB(t)
+
+	if _, err := RunClientRetention(context.Background(), db, ClientRetentionOptions{RetentionDays: 30, Mode: RetentionModeDelete}); err != nil {
+		t.Fatalf("RunClientRetention: %v", err)
+	}
+
+	for _, statement := range *statements {
+		if strings.Contains(statement.sql, "client_daily_rollups") {
+			t.Errorf("delete mode ran the rollup:\n%s", statement.sql)
+		}
+	}
+}
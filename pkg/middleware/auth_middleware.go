@@ -31,7 +31,7 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		tokenString := authHeaderParts[1]
 
-		isBlacklisted, _ := helpers.IsTokenBlacklisted(tokenString)
+		isBlacklisted, _ := helpers.IsTokenBlacklisted(c.Request.Context(), tokenString)
 		if isBlacklisted {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, exceptions.NewException(http.StatusUnauthorized, "Token is blacklisted"))
 			return
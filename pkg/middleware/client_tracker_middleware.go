@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+	"xanny-go/api/users/dto"
+	"xanny-go/models"
+	"xanny-go/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mssola/user_agent"
+	"gorm.io/gorm"
+)
+
+type ClientTrackerOptions struct {
+	BufferSize    int
+	BatchSize     int
+	FlushInterval time.Duration
+	EnqueueWait   time.Duration
+	AnonymizeIP   func(ip string) string
+}
+
+type ClientTrackerStats struct {
+	Enqueued uint64 `json:"enqueued"`
+	Dropped  uint64 `json:"dropped"`
+	Inserted uint64 `json:"inserted"`
+	Failed   uint64 `json:"failed"`
+	Pending  int    `json:"pending"`
+}
+
+type ClientTracker struct {
+	db    *gorm.DB
+	opts  ClientTrackerOptions
+	queue chan models.Clients
+	quit  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+
+	enqueued atomic.Uint64
+	dropped  atomic.Uint64
+	inserted atomic.Uint64
+	failed   atomic.Uint64
+}
+
+func DefaultClientTrackerOptions() ClientTrackerOptions {
+	return ClientTrackerOptions{
+		BufferSize:    4096,
+		BatchSize:     200,
+		FlushInterval: 2 * time.Second,
+		EnqueueWait:   5 * time.Millisecond,
+	}
+}
+
+func NewClientTracker(db *gorm.DB, opts ClientTrackerOptions) *ClientTracker {
+	defaults := DefaultClientTrackerOptions()
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaults.BufferSize
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaults.BatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaults.FlushInterval
+	}
+
+	tracker := &ClientTracker{
+		db:    db,
+		opts:  opts,
+		queue: make(chan models.Clients, opts.BufferSize),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	go tracker.run()
+
+	return tracker
+}
+
+// Middleware records one Clients row per request. The row is built
+// synchronously after the handler returns so nothing reads the gin.Context
+// once it has been recycled; only the insert happens in the background.
+func (t *ClientTracker) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		t.enqueue(t.capture(c, time.Since(start)))
+	}
+}
+
+func (t *ClientTracker) capture(c *gin.Context, latency time.Duration) models.Clients {
+	ua := user_agent.New(c.Request.Header.Get("User-Agent"))
+	name, version := ua.Browser()
+
+	fullURL := url.URL{
+		Path:     c.Request.URL.Path,
+		RawQuery: c.Request.URL.RawQuery,
+	}
+
+	clientIP := c.ClientIP()
+	if t.opts.AnonymizeIP != nil {
+		clientIP = t.opts.AnonymizeIP(clientIP)
+	}
+
+	var userUUID string
+	if user, exists := c.Get("user"); exists {
+		if output, ok := user.(dto.UserOutput); ok {
+			userUUID = output.UUID
+		}
+	}
+
+	return models.Clients{
+		IP:         clientIP,
+		Browser:    name,
+		Version:    version,
+		OS:         ua.OS(),
+		Device:     ua.Platform(),
+		Origin:     c.Request.Referer(),
+		API:        fullURL.String(),
+		StatusCode: c.Writer.Status(),
+		LatencyMs:  float64(latency) / float64(time.Millisecond),
+		UserUUID:   userUUID,
+	}
+}
+
+// enqueue hands the row to the worker. When the buffer is full it waits up to
+// EnqueueWait for room and then drops the row rather than stalling requests.
+func (t *ClientTracker) enqueue(data models.Clients) {
+	select {
+	case <-t.quit:
+		t.dropped.Add(1)
+		return
+	default:
+	}
+
+	select {
+	case t.queue <- data:
+		t.enqueued.Add(1)
+		return
+	default:
+	}
+
+	if t.opts.EnqueueWait <= 0 {
+		t.dropped.Add(1)
+		return
+	}
+
+	timer := time.NewTimer(t.opts.EnqueueWait)
+	defer timer.Stop()
+
+	select {
+	case t.queue <- data:
+		t.enqueued.Add(1)
+	case <-timer.C:
+		t.dropped.Add(1)
+	case <-t.quit:
+		t.dropped.Add(1)
+	}
+}
+
+func (t *ClientTracker) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(t.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.Clients, 0, t.opts.BatchSize)
+
+	for {
+		select {
+		case data := <-t.queue:
+			batch = append(batch, data)
+			if len(batch) >= t.opts.BatchSize {
+				batch = t.flush(batch)
+			}
+
+		case <-ticker.C:
+			batch = t.flush(batch)
+
+		case <-t.quit:
+			for {
+				select {
+				case data := <-t.queue:
+					batch = append(batch, data)
+					if len(batch) >= t.opts.BatchSize {
+						batch = t.flush(batch)
+					}
+				default:
+					t.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (t *ClientTracker) flush(batch []models.Clients) []models.Clients {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if err := t.db.CreateInBatches(batch, t.opts.BatchSize).Error; err != nil {
+		t.failed.Add(uint64(len(batch)))
+		logger.Error("Failed to insert %d client records: %v", len(batch), err)
+	} else {
+		t.inserted.Add(uint64(len(batch)))
+	}
+
+	return batch[:0]
+}
+
+// Stats returns the tracker's counters since startup. Dropped counts rows
+// discarded because the queue stayed full or the tracker was closing.
+func (t *ClientTracker) Stats() ClientTrackerStats {
+	return ClientTrackerStats{
+		Enqueued: t.enqueued.Load(),
+		Dropped:  t.dropped.Load(),
+		Inserted: t.inserted.Load(),
+		Failed:   t.failed.Load(),
+		Pending:  len(t.queue),
+	}
+}
+
+// StatsHandler serves Stats as JSON for the internal routes
+func (t *ClientTracker) StatsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, t.Stats())
+	}
+}
+
+// Close stops accepting new rows and flushes everything still queued. It
+// returns ctx.Err() if the final flush does not finish in time.
+func (t *ClientTracker) Close(ctx context.Context) error {
+	t.once.Do(func() {
+		close(t.quit)
+	})
+
+	select {
+	case <-t.done:
+		stats := t.Stats()
+		logger.Info("Client tracker stopped: %d inserted, %d dropped, %d failed", stats.Inserted, stats.Dropped, stats.Failed)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
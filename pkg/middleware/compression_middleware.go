@@ -0,0 +1,356 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	EncodingBrotli = "br"
+	EncodingZstd   = "zstd"
+	EncodingGzip   = "gzip"
+
+	compressionDisabledKey = "compression_disabled"
+)
+
+type CompressionOptions struct {
+	// Encodings lists the supported encodings in server preference order;
+	// it breaks ties between equal Accept-Encoding q-values.
+	Encodings     []string
+	MinSize       int
+	ContentTypes  []string
+	ExcludedPaths []string
+}
+
+func DefaultCompressionOptions() CompressionOptions {
+	return CompressionOptions{
+		Encodings: []string{EncodingBrotli, EncodingZstd, EncodingGzip},
+		MinSize:   1024,
+		ContentTypes: []string{
+			"application/json",
+			"application/javascript",
+			"application/xml",
+			"application/problem+json",
+			"image/svg+xml",
+			"text/",
+		},
+	}
+}
+
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var compressorPools = map[string]*sync.Pool{
+	EncodingBrotli: {New: func() interface{} { return brotli.NewWriterLevel(nil, 5) }},
+	EncodingZstd: {New: func() interface{} {
+		encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithEncoderLevel(zstd.SpeedDefault))
+		return encoder
+	}},
+	EncodingGzip: {New: func() interface{} {
+		writer, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return writer
+	}},
+}
+
+// CompressionMiddleware compresses responses with the best encoding the
+// client accepts. Bodies are buffered until MinSize bytes have been written so
+// small responses go out uncompressed, and nothing is set on responses that
+// never write a body.
+func CompressionMiddleware(opts CompressionOptions) gin.HandlerFunc {
+	if len(opts.Encodings) == 0 {
+		opts.Encodings = DefaultCompressionOptions().Encodings
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || isExcludedPath(c.Request.URL.Path, opts.ExcludedPaths) {
+			c.Next()
+			return
+		}
+
+		encoding := negotiateEncoding(c.Request.Header.Get("Accept-Encoding"), opts.Encodings)
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &compressWriter{
+			ResponseWriter: original,
+			ctx:            c,
+			opts:           &opts,
+			encoding:       encoding,
+		}
+		c.Writer = writer
+
+		defer func() {
+			writer.finish()
+			c.Writer = original
+		}()
+
+		c.Next()
+	}
+}
+
+// DisableCompression turns compression off for the routes it is attached to.
+func DisableCompression() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(compressionDisabledKey, true)
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks the supported encoding with the highest q-value.
+// An explicit q=0 excludes an encoding; "*" covers any encoding not listed.
+func negotiateEncoding(header string, supported []string) string {
+	if header == "" {
+		return ""
+	}
+
+	qualities := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		if key, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(key) == "q" {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+
+		if name == "*" {
+			wildcard = q
+		} else {
+			qualities[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range supported {
+		q, listed := qualities[encoding]
+		if !listed {
+			q = max(wildcard, 0)
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+func isExcludedPath(path string, excluded []string) bool {
+	for _, prefix := range excluded {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type compressWriter struct {
+	gin.ResponseWriter
+	ctx      *gin.Context
+	opts     *CompressionOptions
+	encoding string
+
+	status     int
+	buf        bytes.Buffer
+	decided    bool
+	compressor compressor
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	if w.decided {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	if w.status == 0 {
+		w.status = w.ResponseWriter.Status()
+	}
+}
+
+func (w *compressWriter) Status() int {
+	if !w.decided && w.status != 0 {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *compressWriter) Written() bool {
+	return w.status != 0 || w.buf.Len() > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf.Write(data)
+		if w.buf.Len() < w.opts.MinSize {
+			return len(data), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+
+	if w.compressor != nil {
+		return w.compressor.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush forces a decision on whatever has been buffered so streamed and SSE
+// responses reach the client immediately, then flushes the encoder.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+	if w.compressor != nil {
+		w.compressor.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) decide() error {
+	w.decided = true
+
+	header := w.ResponseWriter.Header()
+	if w.shouldCompress(header) {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+
+		w.compressor = compressorPools[w.encoding].Get().(compressor)
+		w.compressor.Reset(w.ResponseWriter)
+	}
+
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if w.buf.Len() == 0 {
+		return nil
+	}
+
+	var err error
+	if w.compressor != nil {
+		_, err = w.compressor.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+func (w *compressWriter) shouldCompress(header http.Header) bool {
+	if disabled, _ := w.ctx.Get(compressionDisabledKey); disabled == true {
+		return false
+	}
+
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	status := w.status
+	if status == 0 {
+		status = w.ResponseWriter.Status()
+	}
+
+	switch {
+	case status < http.StatusOK, status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(w.buf.Bytes())
+	}
+	if !w.allowedContentType(contentType) {
+		return false
+	}
+
+	addVary(header, "Accept-Encoding")
+
+	// Streams that flush before reaching MinSize are still compressed; only
+	// complete bodies are judged by their size.
+	return w.buf.Len() >= w.opts.MinSize || isEventStream(contentType)
+}
+
+func (w *compressWriter) allowedContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range w.opts.ContentTypes {
+		if strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed) {
+			return true
+		}
+		if mediaType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// finish runs after the handler chain. Responses that never wrote anything
+// are left untouched; buffered bodies below MinSize are sent as-is.
+func (w *compressWriter) finish() {
+	if !w.decided {
+		if w.status == 0 && w.buf.Len() == 0 {
+			return
+		}
+		w.decide()
+	}
+
+	if w.compressor != nil {
+		w.compressor.Close()
+		w.compressor.Reset(nil)
+		compressorPools[w.encoding].Put(w.compressor)
+		w.compressor = nil
+	}
+}
+
+func isEventStream(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/event-stream")
+}
+
+func addVary(header http.Header, value string) {
+	for _, existing := range header.Values("Vary") {
+		for _, token := range strings.Split(existing, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), value) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", value)
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"sort"
+	"strings"
+	"xanny-go/pkg/config"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+type CORSOverride struct {
+	PathPrefix string
+	Policy     config.CORSPolicy
+}
+
+// CORSMiddleware applies policy to every request, except requests whose path
+// starts with an override's PathPrefix, which use that override instead. It is
+// installed once on the engine so preflight requests for unregistered OPTIONS
+// routes are still answered.
+func CORSMiddleware(policy config.CORSPolicy, overrides ...CORSOverride) gin.HandlerFunc {
+	defaultHandler := newCORSHandler(policy)
+
+	type prefixHandler struct {
+		prefix  string
+		handler gin.HandlerFunc
+	}
+
+	handlers := make([]prefixHandler, 0, len(overrides))
+	for _, override := range overrides {
+		handlers = append(handlers, prefixHandler{
+			prefix:  override.PathPrefix,
+			handler: newCORSHandler(override.Policy),
+		})
+	}
+
+	sort.Slice(handlers, func(i, j int) bool {
+		return len(handlers[i].prefix) > len(handlers[j].prefix)
+	})
+
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		for _, h := range handlers {
+			if strings.HasPrefix(path, h.prefix) {
+				h.handler(c)
+				return
+			}
+		}
+
+		defaultHandler(c)
+	}
+}
+
+func newCORSHandler(policy config.CORSPolicy) gin.HandlerFunc {
+	corsConfig := cors.Config{
+		AllowMethods:     policy.AllowedMethods,
+		AllowHeaders:     policy.AllowedHeaders,
+		ExposeHeaders:    policy.ExposedHeaders,
+		AllowCredentials: policy.AllowCredentials,
+		MaxAge:           policy.MaxAge,
+	}
+
+	if policy.AllowsAnyOrigin() && !policy.AllowCredentials {
+		corsConfig.AllowAllOrigins = true
+	} else {
+		corsConfig.AllowOriginFunc = policy.AllowsOrigin
+	}
+
+	return cors.New(corsConfig)
+}
@@ -1,41 +0,0 @@
-package middleware
-
-import (
-	"compress/gzip"
-	"io"
-	"strings"
-
-	"github.com/gin-gonic/gin"
-)
-
-func GzipResponseMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		if !strings.Contains(c.Request.Header.Get("Accept-Encoding"), "gzip") {
-			c.Next()
-			return
-		}
-
-		gzipWriter := gzip.NewWriter(c.Writer)
-		defer gzipWriter.Close()
-
-		wrappedWriter := &gzipResponseWriter{
-			ResponseWriter: c.Writer,
-			Writer:         gzipWriter,
-		}
-
-		c.Writer = wrappedWriter
-		c.Writer.Header().Set("Content-Encoding", "gzip")
-		c.Writer.Header().Set("Vary", "Accept-Encoding")
-
-		c.Next()
-	}
-}
-
-type gzipResponseWriter struct {
-	gin.ResponseWriter
-	Writer io.Writer
-}
-
-func (g *gzipResponseWriter) Write(data []byte) (int, error) {
-	return g.Writer.Write(data)
-}
\ No newline at end of file
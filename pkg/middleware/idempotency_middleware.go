@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+	"xanny-go/api/users/dto"
+	"xanny-go/pkg/cache"
+	"xanny-go/pkg/exceptions"
+	"xanny-go/pkg/helpers"
+	"xanny-go/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+)
+
+type IdempotencyOptions struct {
+	// TTL is how long a completed response is replayed for.
+	TTL time.Duration
+	// LockTTL bounds how long an in-flight request holds its key, so a
+	// crashed handler does not block retries forever.
+	LockTTL      time.Duration
+	Required     bool
+	MaxKeyLength int
+}
+
+type idempotencyRecord struct {
+	Fingerprint string      `json:"fingerprint"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+}
+
+func DefaultIdempotencyOptions() IdempotencyOptions {
+	return IdempotencyOptions{
+		TTL:          24 * time.Hour,
+		LockTTL:      30 * time.Second,
+		MaxKeyLength: 255,
+	}
+}
+
+// IdempotencyMiddleware makes unsafe requests carrying an Idempotency-Key
+// header safe to retry. The first request runs the handler and its response is
+// stored; repeats with the same key and body get the stored response back,
+// repeats with a different body are rejected with 422, and repeats arriving
+// while the first is still running get 409. Keys are scoped to the method,
+// route and signed-in user; anonymous requests are matched on method and route
+// alone, so a retry from a new IP still replays.
+// Server errors are not stored so they can be retried.
+func IdempotencyMiddleware(store cache.Cache, opts IdempotencyOptions) gin.HandlerFunc {
+	locker, err := cache.NewLocker(store, nil)
+	if err != nil {
+		logger.PanicError("Idempotency store cannot hold locks: %v", err)
+	}
+
+	defaults := DefaultIdempotencyOptions()
+	if opts.TTL <= 0 {
+		opts.TTL = defaults.TTL
+	}
+	if opts.LockTTL <= 0 {
+		opts.LockTTL = defaults.LockTTL
+	}
+	if opts.MaxKeyLength <= 0 {
+		opts.MaxKeyLength = defaults.MaxKeyLength
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			if opts.Required {
+				c.AbortWithStatusJSON(http.StatusBadRequest, exceptions.NewException(http.StatusBadRequest, exceptions.ErrIdempotencyKeyRequired))
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if len(key) > opts.MaxKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, exceptions.NewException(http.StatusBadRequest, exceptions.ErrIdempotencyKeyInvalid))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			exc := helpers.DecodeException(err)
+			c.AbortWithStatusJSON(exc.Status, exc)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		recordKey := "idempotency:" + idempotencyScope(c) + ":" + c.Request.Method + ":" + c.FullPath() + ":" + key
+		fingerprint := requestFingerprint(c.Request, body)
+
+		if replayIdempotentResponse(c, store, recordKey, fingerprint) {
+			return
+		}
+
+		lock, err := locker.TryAcquire(ctx, recordKey, opts.LockTTL)
+		if errors.Is(err, cache.ErrLockNotAcquired) {
+			c.AbortWithStatusJSON(http.StatusConflict, exceptions.NewException(http.StatusConflict, exceptions.ErrIdempotencyKeyInUse))
+			return
+		}
+		if err != nil {
+			logger.Warning("Idempotency lock unavailable, running request without it: %v", err)
+			c.Next()
+			return
+		}
+
+		// Keep the lock while a slow handler runs so a retry cannot start a
+		// second execution once LockTTL passes.
+		lockCtx := context.WithoutCancel(ctx)
+		_, stopKeepAlive := lock.KeepAlive(lockCtx)
+		defer func() {
+			stopKeepAlive()
+			if err := lock.Release(lockCtx); err != nil {
+				logger.Warning("Idempotency lock release failed: %v", err)
+			}
+		}()
+
+		// The previous holder may have finished between the lookup and the lock.
+		if replayIdempotentResponse(c, store, recordKey, fingerprint) {
+			return
+		}
+
+		before := c.Writer.Header().Clone()
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+
+		record, err := json.Marshal(idempotencyRecord{
+			Fingerprint: fingerprint,
+			Status:      status,
+			Header:      handlerHeaders(before, writer.Header()),
+			Body:        writer.body.Bytes(),
+		})
+		if err != nil {
+			logger.Error("Failed to encode idempotent response: %v", err)
+			return
+		}
+
+		if err := store.Set(lockCtx, recordKey, record, opts.TTL); err != nil {
+			logger.Error("Failed to store idempotent response: %v", err)
+		}
+	}
+}
+
+// replayIdempotentResponse writes the stored response for recordKey, if any,
+// and reports whether the request has been answered.
+func replayIdempotentResponse(c *gin.Context, store cache.Cache, recordKey, fingerprint string) bool {
+	data, err := store.Get(c.Request.Context(), recordKey)
+	if err != nil || data == nil {
+		return false
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		logger.Warning("Discarding unreadable idempotent response for %s: %v", recordKey, err)
+		return false
+	}
+
+	if record.Fingerprint != fingerprint {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, exceptions.NewException(http.StatusUnprocessableEntity, exceptions.ErrIdempotencyKeyMismatch))
+		return true
+	}
+
+	header := c.Writer.Header()
+	for name, values := range record.Header {
+		header[name] = values
+	}
+	header.Set(IdempotencyReplayedHeader, "true")
+
+	c.Status(record.Status)
+	c.Writer.Write(record.Body)
+	c.Abort()
+	return true
+}
+
+// handlerHeaders returns the headers set while the handler ran. Headers from
+// outer middleware are left out because they are set again on replay, as are
+// the encoding headers the compression middleware manages.
+func handlerHeaders(before, after http.Header) http.Header {
+	headers := make(http.Header)
+	for name, values := range after {
+		switch name {
+		case "Content-Encoding", "Content-Length", "Vary":
+			continue
+		}
+		if slices.Equal(before[name], values) {
+			continue
+		}
+		headers[name] = slices.Clone(values)
+	}
+	return headers
+}
+
+// idempotencyScope identifies the user a key belongs to. Anonymous keys
+// share one scope rather than being tied to the client IP, so a client that
+// retries from another network still gets its stored response; a key reused
+// with a different request is rejected by the fingerprint check.
+func idempotencyScope(c *gin.Context) string {
+	if user, exists := c.Get("user"); exists {
+		if output, ok := user.(dto.UserOutput); ok && output.UUID != "" {
+			return "user:" + output.UUID
+		}
+	}
+	return "anonymous"
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(r.URL.RequestURI()))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// idempotencyWriter copies the response body as it is written through.
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
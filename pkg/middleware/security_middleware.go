@@ -0,0 +1,286 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"xanny-go/pkg/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// CSPNonceSource is replaced with 'nonce-<value>' when a policy is built
+	// for a request.
+	CSPNonceSource = "'nonce'"
+
+	cspNonceKey = "csp_nonce"
+)
+
+type CSPBuilder struct {
+	directives []cspDirective
+}
+
+type cspDirective struct {
+	name    string
+	sources []string
+}
+
+func NewCSPBuilder() *CSPBuilder {
+	return &CSPBuilder{}
+}
+
+// Set replaces the sources of a directive, adding it if it is not present.
+func (b *CSPBuilder) Set(directive string, sources ...string) *CSPBuilder {
+	for i := range b.directives {
+		if b.directives[i].name == directive {
+			b.directives[i].sources = sources
+			return b
+		}
+	}
+
+	b.directives = append(b.directives, cspDirective{name: directive, sources: sources})
+	return b
+}
+
+// Add appends sources to a directive, adding it if it is not present.
+func (b *CSPBuilder) Add(directive string, sources ...string) *CSPBuilder {
+	for i := range b.directives {
+		if b.directives[i].name == directive {
+			b.directives[i].sources = append(b.directives[i].sources, sources...)
+			return b
+		}
+	}
+
+	return b.Set(directive, sources...)
+}
+
+func (b *CSPBuilder) Clone() *CSPBuilder {
+	clone := &CSPBuilder{directives: make([]cspDirective, len(b.directives))}
+	for i, d := range b.directives {
+		clone.directives[i] = cspDirective{name: d.name, sources: append([]string(nil), d.sources...)}
+	}
+	return clone
+}
+
+func (b *CSPBuilder) UsesNonce() bool {
+	for _, d := range b.directives {
+		for _, source := range d.sources {
+			if source == CSPNonceSource {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (b *CSPBuilder) Build(nonce string) string {
+	parts := make([]string, 0, len(b.directives))
+	for _, d := range b.directives {
+		sources := make([]string, 0, len(d.sources))
+		for _, source := range d.sources {
+			if source == CSPNonceSource {
+				if nonce == "" {
+					continue
+				}
+				source = "'nonce-" + nonce + "'"
+			}
+			sources = append(sources, source)
+		}
+
+		if len(sources) == 0 {
+			parts = append(parts, d.name)
+		} else {
+			parts = append(parts, d.name+" "+strings.Join(sources, " "))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// DefaultAPICSP locks a JSON API down completely: nothing may be loaded,
+// framed, or submitted.
+func DefaultAPICSP() *CSPBuilder {
+	return NewCSPBuilder().
+		Set("default-src", "'none'").
+		Set("frame-ancestors", "'none'").
+		Set("base-uri", "'none'").
+		Set("form-action", "'none'")
+}
+
+// SwaggerCSP allows the swagger UI to run with nonce-tagged inline script and
+// style. Development additionally allows 'unsafe-inline' and 'unsafe-eval' so
+// locally customised swagger templates keep working.
+func SwaggerCSP(development bool) *CSPBuilder {
+	builder := NewCSPBuilder().
+		Set("default-src", "'self'").
+		Set("script-src", "'self'", CSPNonceSource).
+		Set("style-src", "'self'", CSPNonceSource).
+		Set("img-src", "'self'", "data:").
+		Set("font-src", "'self'", "data:").
+		Set("connect-src", "'self'").
+		Set("frame-ancestors", "'none'").
+		Set("base-uri", "'self'").
+		Set("form-action", "'self'")
+
+	if development {
+		builder.Set("script-src", "'self'", "'unsafe-inline'", "'unsafe-eval'")
+		builder.Set("style-src", "'self'", "'unsafe-inline'")
+	}
+
+	return builder
+}
+
+// SecurityHeadersMiddleware sets the transport and browser hardening headers
+// on every response. The CSP comes from cfg when configured, otherwise from
+// DefaultAPICSP; use CSPMiddleware to override it for a route group.
+func SecurityHeadersMiddleware(cfg config.SecurityHeadersConfig) gin.HandlerFunc {
+	hsts := buildHSTS(cfg)
+
+	csp := cfg.ContentSecurityPolicy
+	if csp == "" {
+		builder := DefaultAPICSP()
+		if cfg.CSPReportURI != "" {
+			builder.Set("report-uri", cfg.CSPReportURI)
+		}
+		csp = builder.Build("")
+	}
+
+	return func(c *gin.Context) {
+		headers := c.Writer.Header()
+
+		if hsts != "" {
+			headers.Set("Strict-Transport-Security", hsts)
+		}
+		headers.Set("X-Content-Type-Options", "nosniff")
+		if cfg.FrameOptions != "" {
+			headers.Set("X-Frame-Options", cfg.FrameOptions)
+		}
+		if cfg.ReferrerPolicy != "" {
+			headers.Set("Referrer-Policy", cfg.ReferrerPolicy)
+		}
+		if cfg.PermissionsPolicy != "" {
+			headers.Set("Permissions-Policy", cfg.PermissionsPolicy)
+		}
+		headers.Set("Content-Security-Policy", csp)
+
+		c.Next()
+	}
+}
+
+// CSPMiddleware overrides the Content-Security-Policy for a route or group.
+// When the policy uses CSPNonceSource a fresh nonce is generated per request
+// and added to every <script> and <style> tag of HTML responses.
+func CSPMiddleware(builder *CSPBuilder, cfg config.SecurityHeadersConfig) gin.HandlerFunc {
+	builder = builder.Clone()
+	if cfg.CSPReportURI != "" {
+		builder.Set("report-uri", cfg.CSPReportURI)
+	}
+	useNonce := builder.UsesNonce()
+
+	return func(c *gin.Context) {
+		if !useNonce {
+			c.Header("Content-Security-Policy", builder.Build(""))
+			c.Next()
+			return
+		}
+
+		nonce := CSPNonce(c)
+		c.Header("Content-Security-Policy", builder.Build(nonce))
+
+		writer := &nonceWriter{ResponseWriter: c.Writer, nonce: nonce}
+		c.Writer = writer
+		c.Next()
+		writer.finish()
+	}
+}
+
+// CSPNonce returns the nonce for the current request, generating it on first
+// use so handlers rendering their own HTML can tag inline elements.
+func CSPNonce(c *gin.Context) string {
+	if nonce := c.GetString(cspNonceKey); nonce != "" {
+		return nonce
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+
+	nonce := base64.StdEncoding.EncodeToString(b)
+	c.Set(cspNonceKey, nonce)
+	return nonce
+}
+
+func buildHSTS(cfg config.SecurityHeadersConfig) string {
+	if cfg.HSTSMaxAge <= 0 {
+		return ""
+	}
+
+	value := fmt.Sprintf("max-age=%d", int64(cfg.HSTSMaxAge.Seconds()))
+	if cfg.HSTSIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if cfg.HSTSPreload {
+		value += "; preload"
+	}
+	return value
+}
+
+// nonceWriter buffers HTML bodies so inline <script> and <style> tags can be
+// tagged with the request nonce. Other content types pass straight through.
+type nonceWriter struct {
+	gin.ResponseWriter
+	nonce     string
+	buffering bool
+	decided   bool
+	status    int
+	body      bytes.Buffer
+}
+
+func (w *nonceWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	w.buffering = strings.HasPrefix(w.ResponseWriter.Header().Get("Content-Type"), "text/html")
+}
+
+func (w *nonceWriter) WriteHeader(code int) {
+	w.decide()
+	if w.buffering {
+		w.status = code
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *nonceWriter) Write(data []byte) (int, error) {
+	w.decide()
+	if w.buffering {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *nonceWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *nonceWriter) finish() {
+	if !w.buffering {
+		return
+	}
+
+	attr := []byte(` nonce="` + w.nonce + `"`)
+	body := w.body.Bytes()
+	body = bytes.ReplaceAll(body, []byte("<script"), append([]byte("<script"), attr...))
+	body = bytes.ReplaceAll(body, []byte("<style"), append([]byte("<style"), attr...))
+
+	w.ResponseWriter.Header().Del("Content-Length")
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	w.ResponseWriter.Write(body)
+}
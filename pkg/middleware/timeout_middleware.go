@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"xanny-go/pkg/exceptions"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TimeoutMiddleware attaches a deadline to the request context. GORM and Redis
+// calls made with the context give up once it expires, and if the handler has
+// not written anything by then the client gets a 504, or a 503 when the
+// request was cancelled. Nested timeouts can only shorten the deadline, so
+// attach the tightest one to the route itself.
+func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if c.Writer.Written() {
+			return
+		}
+
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			c.AbortWithStatusJSON(http.StatusGatewayTimeout, exceptions.NewException(http.StatusGatewayTimeout, exceptions.ErrRequestTimeout))
+		case context.Canceled:
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, exceptions.NewException(http.StatusServiceUnavailable, exceptions.ErrRequestCanceled))
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"xanny-go/pkg/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+const contextKey = "session"
+
+// Manager carries sessions in an HttpOnly cookie configured by the
+// SESSION_* settings. middleware.SessionMiddleware puts it in front of
+// routes; controllers use it to sign users in and out.
+type Manager struct {
+	store  *Store
+	config config.SessionConfig
+}
+
+func NewManager(store *Store, sessionConfig config.SessionConfig) *Manager {
+	return &Manager{
+		store:  store,
+		config: sessionConfig,
+	}
+}
+
+// Start signs userID in on a new session and sets its cookie. Any session
+// the browser already carried is destroyed first, so an ID planted before
+// login never becomes authenticated.
+func (m *Manager) Start(c *gin.Context, userID string, values map[string]string) (*Session, error) {
+	if id, err := c.Cookie(m.config.CookieName); err == nil {
+		if err := m.store.Destroy(c.Request.Context(), id); err != nil {
+			return nil, err
+		}
+	}
+
+	session, err := m.store.Create(c.Request.Context(), userID, values)
+	if err != nil {
+		return nil, err
+	}
+
+	m.setCookie(c, session.ID, int(m.store.Options().AbsoluteTTL.Seconds()))
+	return session, nil
+}
+
+// End destroys the current session and clears its cookie
+func (m *Manager) End(c *gin.Context) error {
+	m.setCookie(c, "", -1)
+
+	id, err := c.Cookie(m.config.CookieName)
+	if err != nil {
+		return nil
+	}
+	return m.store.Destroy(c.Request.Context(), id)
+}
+
+// Load returns the session named by the request's cookie, extending it by
+// the idle TTL at most once every tenth of it. It returns ErrNotFound, and
+// clears a stale cookie, when there is no live session.
+func (m *Manager) Load(c *gin.Context) (*Session, error) {
+	id, err := c.Cookie(m.config.CookieName)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	session, err := m.store.Get(c.Request.Context(), id)
+	if err == nil && time.Since(session.LastSeen) > m.store.Options().IdleTTL/10 {
+		err = m.store.Touch(c.Request.Context(), session)
+	}
+	if errors.Is(err, ErrNotFound) {
+		m.setCookie(c, "", -1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// SetContext stores session on c for FromContext
+func SetContext(c *gin.Context, session *Session) {
+	c.Set(contextKey, session)
+}
+
+// FromContext returns the session loaded by middleware.SessionMiddleware
+func FromContext(c *gin.Context) (*Session, bool) {
+	value, ok := c.Get(contextKey)
+	if !ok {
+		return nil, false
+	}
+	session, ok := value.(*Session)
+	return session, ok
+}
+
+func (m *Manager) setCookie(c *gin.Context, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     m.config.CookieName,
+		Value:    value,
+		Path:     "/",
+		Domain:   m.config.CookieDomain,
+		MaxAge:   maxAge,
+		Secure:   m.config.CookieSecure,
+		HttpOnly: true,
+		SameSite: m.config.SameSite(),
+	})
+}
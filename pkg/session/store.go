@@ -0,0 +1,211 @@
+// Package session keeps server-side sessions for first-party browser
+// clients in a cache of their own
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"xanny-go/pkg/cache"
+)
+
+// ErrNotFound is returned for unknown, expired or destroyed sessions
+var ErrNotFound = errors.New("session not found")
+
+// Session is server-side state for one signed-in browser. The ID is only
+// ever sent to the client; the cache key is derived from its hash, so keys
+// read from the cache cannot be replayed as cookies
+type Session struct {
+	ID        string            `json:"-"`
+	UserID    string            `json:"user_id"`
+	Values    map[string]string `json:"values,omitempty"`
+	CSRFToken string            `json:"csrf_token"`
+	CreatedAt time.Time         `json:"created_at"`
+	LastSeen  time.Time         `json:"last_seen"`
+}
+
+// Options configures a Store
+type Options struct {
+	// Prefix is prepended to session keys
+	Prefix string
+	// IdleTTL is how long a session lives without requests. Each Touch
+	// extends it again
+	IdleTTL time.Duration
+	// AbsoluteTTL caps a session's lifetime however active it is
+	AbsoluteTTL time.Duration
+}
+
+// DefaultOptions returns default session options
+func DefaultOptions() *Options {
+	return &Options{
+		Prefix:      "session:",
+		IdleTTL:     30 * time.Minute,
+		AbsoluteTTL: 24 * time.Hour,
+	}
+}
+
+// Store keeps sessions in a cache with rolling expiry
+type Store struct {
+	cache  cache.Cache
+	setter cache.ExistingSetter
+	opts   *Options
+}
+
+// NewStore creates a session store on c, which must implement
+// cache.ExistingSetter so that Touch cannot revive a destroyed session. Give
+// sessions a cache of their own, such as a RedisCache on the shared client,
+// so admin flushes, eviction and snapshots of the application cache never
+// reach them. Use a shared backend when several instances serve the same users
+func NewStore(c cache.Cache, opts *Options) (*Store, error) {
+	setter, ok := cache.AsExistingSetter(c)
+	if !ok {
+		return nil, fmt.Errorf("cache %T cannot back a session store", c)
+	}
+
+	defaults := DefaultOptions()
+	if opts == nil {
+		opts = defaults
+	}
+	if opts.IdleTTL <= 0 {
+		opts.IdleTTL = defaults.IdleTTL
+	}
+	if opts.AbsoluteTTL <= 0 {
+		opts.AbsoluteTTL = defaults.AbsoluteTTL
+	}
+
+	return &Store{
+		cache:  c,
+		setter: setter,
+		opts:   opts,
+	}, nil
+}
+
+// Options returns the store's options
+func (s *Store) Options() *Options {
+	return s.opts
+}
+
+// Create starts a session for userID with a fresh ID and CSRF token
+func (s *Store) Create(ctx context.Context, userID string, values map[string]string) (*Session, error) {
+	id, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	csrfToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &Session{
+		ID:        id,
+		UserID:    userID,
+		Values:    values,
+		CSRFToken: csrfToken,
+		CreatedAt: now,
+		LastSeen:  now,
+	}
+
+	data, ttl, err := s.encode(session)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cache.Set(ctx, s.key(id), data, ttl); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Get loads the session with id, returning ErrNotFound if it does not exist
+// or has outlived AbsoluteTTL
+func (s *Store) Get(ctx context.Context, id string) (*Session, error) {
+	if id == "" {
+		return nil, ErrNotFound
+	}
+
+	data, err := s.cache.Get(ctx, s.key(id))
+	if errors.Is(err, cache.ErrKeyNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	if s.remaining(&session, time.Now()) <= 0 {
+		return nil, ErrNotFound
+	}
+
+	session.ID = id
+	return &session, nil
+}
+
+// Touch records activity on session and extends it by IdleTTL, without
+// going past AbsoluteTTL. It only overwrites a session that still exists,
+// so a request racing Destroy cannot bring the session back, and returns
+// ErrNotFound in that case
+func (s *Store) Touch(ctx context.Context, session *Session) error {
+	session.LastSeen = time.Now()
+
+	data, ttl, err := s.encode(session)
+	if err != nil {
+		return err
+	}
+	updated, err := s.setter.SetXX(ctx, s.key(session.ID), data, ttl)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Destroy removes the session with id
+func (s *Store) Destroy(ctx context.Context, id string) error {
+	if id == "" {
+		return nil
+	}
+	return s.cache.Delete(ctx, s.key(id))
+}
+
+// encode returns session as JSON with its remaining lifetime
+func (s *Store) encode(session *Session) ([]byte, time.Duration, error) {
+	ttl := s.remaining(session, session.LastSeen)
+	if ttl <= 0 {
+		return nil, 0, ErrNotFound
+	}
+
+	data, err := json.Marshal(session)
+	return data, ttl, err
+}
+
+// remaining is how long session may still live after now
+func (s *Store) remaining(session *Session, now time.Time) time.Duration {
+	absolute := session.CreatedAt.Add(s.opts.AbsoluteTTL).Sub(now)
+	return min(s.opts.IdleTTL, absolute)
+}
+
+// key is the cache key for a session ID
+func (s *Store) key(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return s.opts.Prefix + hex.EncodeToString(sum[:])
+}
+
+// randomToken returns 256 random bits, URL-safe encoded
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate session token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,118 @@
+package session_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"xanny-go/pkg/cache"
+	"xanny-go/pkg/session"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+var testOptions = session.Options{
+	Prefix:      "session:",
+	IdleTTL:     time.Minute,
+	AbsoluteTTL: time.Hour,
+}
+
+func newRedisCache(t *testing.T) (*cache.RedisCache, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return cache.NewRedisCache(client, &cache.CacheOptions{}), server
+}
+
+func newStore(t *testing.T, c cache.Cache) *session.Store {
+	t.Helper()
+
+	opts := testOptions
+	store, err := session.NewStore(c, &opts)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	return store
+}
+
+func TestStoreTouchDoesNotReviveDestroyedSession(t *testing.T) {
+	backends := map[string]func(t *testing.T) cache.Cache{
+		"memory": func(t *testing.T) cache.Cache {
+			return cache.Instrument("sessions", cache.NewMemoryCache(nil))
+		},
+		"redis": func(t *testing.T) cache.Cache {
+			c, _ := newRedisCache(t)
+			return c
+		},
+		"tiered": func(t *testing.T) cache.Cache {
+			l2, _ := newRedisCache(t)
+			return cache.NewTieredCache(cache.NewMemoryCache(nil), l2, nil)
+		},
+	}
+
+	for name, newCache := range backends {
+		t.Run(name, func(t *testing.T) {
+			c := newCache(t)
+			t.Cleanup(func() { c.Close() })
+			store := newStore(t, c)
+			ctx := context.Background()
+
+			created, err := store.Create(ctx, "user-1", map[string]string{"email": "user@example.com"})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			loaded, err := store.Get(ctx, created.ID)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if loaded.UserID != "user-1" || loaded.Values["email"] != "user@example.com" || loaded.CSRFToken != created.CSRFToken {
+				t.Errorf("Get = %+v, want the created session %+v", loaded, created)
+			}
+			if err := store.Touch(ctx, loaded); err != nil {
+				t.Errorf("Touch of a live session: %v", err)
+			}
+
+			if err := store.Destroy(ctx, created.ID); err != nil {
+				t.Fatalf("Destroy: %v", err)
+			}
+
+			if err := store.Touch(ctx, loaded); !errors.Is(err, session.ErrNotFound) {
+				t.Errorf("Touch after Destroy = %v, want ErrNotFound", err)
+			}
+			if _, err := store.Get(ctx, created.ID); !errors.Is(err, session.ErrNotFound) {
+				t.Errorf("Get after Destroy and Touch = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestStoreTouchExtendsIdleTTL(t *testing.T) {
+	c, server := newRedisCache(t)
+	store := newStore(t, c)
+	ctx := context.Background()
+
+	created, err := store.Create(ctx, "user-1", nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	server.FastForward(45 * time.Second)
+	if err := store.Touch(ctx, created); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+
+	server.FastForward(45 * time.Second)
+	if _, err := store.Get(ctx, created.ID); err != nil {
+		t.Errorf("Get within the extended idle TTL: %v", err)
+	}
+
+	server.FastForward(time.Minute)
+	if _, err := store.Get(ctx, created.ID); !errors.Is(err, session.ErrNotFound) {
+		t.Errorf("Get after the idle TTL = %v, want ErrNotFound", err)
+	}
+}
@@ -2,8 +2,14 @@ package routers
 
 import (
 	"net/http"
+	"time"
 	"xanny-go/injectors"
+	"xanny-go/pkg/cache"
+	"xanny-go/pkg/config"
 	"xanny-go/pkg/helpers"
+	"xanny-go/pkg/logger"
+	"xanny-go/pkg/middleware"
+	"xanny-go/pkg/session"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -12,9 +18,22 @@ import (
 	"gorm.io/gorm"
 )
 
-func CompRouters(r *gin.RouterGroup, db *gorm.DB, validate *validator.Validate) {
+// Middlewares are the shared handlers feature routers attach to individual
+// routes
+type Middlewares struct {
+	// Idempotency replays responses to retried POSTs carrying an
+	// Idempotency-Key header
+	Idempotency gin.HandlerFunc
+	// ResponseCache caches GET responses for CACHE_RESPONSE_TTL, skipping
+	// requests with a bearer token or session cookie
+	ResponseCache gin.HandlerFunc
+}
+
+func CompRouters(r *gin.RouterGroup, db *gorm.DB, validate *validator.Validate, appCache cache.Cache) {
 	// Swagger documentation endpoint
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	securityConfig := config.GetSecurityHeadersConfig()
+	swaggerCSP := middleware.CSPMiddleware(middleware.SwaggerCSP(securityConfig.Development), securityConfig)
+	r.GET("/swagger/*any", swaggerCSP, ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// Health check endpoint
 	// @Summary Health check
@@ -36,7 +55,42 @@ func CompRouters(r *gin.RouterGroup, db *gorm.DB, validate *validator.Validate)
 		ctx.JSON(statusCode, health)
 	})
 
-	userController := injectors.InitializeUserController(db, validate)
+	sessionConfig := config.GetSessionConfig()
+	if err := sessionConfig.Validate(config.IsProduction()); err != nil {
+		logger.PanicError("Invalid session config: %v", err)
+	}
+
+	// Sessions get a cache of their own on the shared client, out of reach of
+	// the application cache's flush, eviction and snapshots
+	sessionStore, err := session.NewStore(cache.NewRedisCache(config.RedisClient, &cache.CacheOptions{}), &session.Options{
+		Prefix:      "session:",
+		IdleTTL:     sessionConfig.IdleTTL,
+		AbsoluteTTL: sessionConfig.AbsoluteTTL,
+	})
+	if err != nil {
+		logger.PanicError("Failed to create session store: %v", err)
+	}
+	sessions := session.NewManager(sessionStore, sessionConfig)
+
+	userController := injectors.InitializeUserController(db, validate, sessions)
+
+	idempotencyStore := cache.NewRedisCache(config.RedisClient, &cache.CacheOptions{DefaultTTL: 24 * time.Hour})
+
+	responseCacheOptions := cache.DefaultCacheMiddlewareOptions(appCache)
+	responseCacheOptions.DefaultTTL = config.GetCacheResponseTTL()
+	responseCacheOptions.SkipCache = func(c *gin.Context) bool {
+		if c.GetHeader("Authorization") != "" {
+			return true
+		}
+		_, err := c.Cookie(sessionConfig.CookieName)
+		return err == nil
+	}
+
+	middlewares := Middlewares{
+		Idempotency:   middleware.IdempotencyMiddleware(idempotencyStore, middleware.DefaultIdempotencyOptions()),
+		ResponseCache: cache.CacheMiddleware(responseCacheOptions),
+	}
 
-	UserRoutes(r, userController)
+	UserRoutes(r, userController, middlewares)
+	SessionRoutes(r, userController, sessions)
 }
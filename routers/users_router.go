@@ -2,18 +2,35 @@ package routers
 
 import (
 	"xanny-go/api/users/controllers"
+	"xanny-go/pkg/middleware"
+	"xanny-go/pkg/session"
 
 	"github.com/gin-gonic/gin"
 )
 
-func UserRoutes(r *gin.RouterGroup, userController controllers.CompControllers) {
-	userGroup := r.Group("/user")
+// userBodyLimit is well above any user payload; auth endpoints have no reason
+// to accept the global limit.
+const userBodyLimit = 16 << 10
+
+func UserRoutes(r *gin.RouterGroup, userController controllers.CompControllers, middlewares Middlewares) {
+	userGroup := r.Group("/user", middleware.BodyLimitMiddleware(userBodyLimit))
 	{
-		userGroup.POST("/create", userController.Create)
-		userGroup.POST("/resend", userController.ResendVerificationEmail)
-		userGroup.POST("/verify", userController.VerificationEmail)
+		userGroup.POST("/create", middlewares.Idempotency, userController.Create)
+		userGroup.POST("/resend", middlewares.Idempotency, userController.ResendVerificationEmail)
+		userGroup.POST("/verify", middlewares.Idempotency, userController.VerificationEmail)
 		userGroup.POST("/login", userController.Login)
 		userGroup.POST("/refresh", userController.Refresh)
 		userGroup.POST("/logout", userController.Logout)
 	}
 }
+
+// SessionRoutes serves the same accounts over cookie sessions for first-party
+// browser clients. Unsafe requests need the X-CSRF-Token header.
+func SessionRoutes(r *gin.RouterGroup, userController controllers.CompControllers, sessions *session.Manager) {
+	sessionGroup := r.Group("/session", middleware.BodyLimitMiddleware(userBodyLimit))
+	{
+		sessionGroup.POST("/login", userController.SessionLogin)
+		sessionGroup.GET("", middleware.SessionMiddleware(sessions), userController.Session)
+		sessionGroup.POST("/logout", middleware.SessionMiddleware(sessions), middleware.CSRFMiddleware(), userController.SessionLogout)
+	}
+}